@@ -0,0 +1,746 @@
+package toolpaths
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Errors returned by Cascade methods.
+var (
+	// ErrScopeNotFound is returned when a scope name is not configured.
+	ErrScopeNotFound = errors.New("toolpaths: scope not found")
+
+	// ErrScopeNotWritable is returned when a write targets a read-only scope.
+	ErrScopeNotWritable = errors.New("toolpaths: scope is not writable")
+
+	// ErrNoProjectRoot is returned when a project-relative path is requested
+	// but no project root was detected.
+	ErrNoProjectRoot = errors.New("toolpaths: no project root found")
+
+	// ErrProjectDirNotFound is returned when a project directory type or
+	// named subdirectory is not configured.
+	ErrProjectDirNotFound = errors.New("toolpaths: project directory not configured")
+
+	// ErrProjectFileNotFound is returned when a known file name is not configured.
+	ErrProjectFileNotFound = errors.New("toolpaths: project file not configured")
+
+	// ErrScopeUnavailable is returned when a scope has no directory here,
+	// such as a managed scope over a Dirs without managed directories.
+	ErrScopeUnavailable = errors.New("toolpaths: scope is unavailable")
+)
+
+// Built-in scope names used by DefaultScopes.
+const (
+	ScopeLocal   = "local"
+	ScopeProject = "project"
+	ScopeUser    = "user"
+	ScopeSystem  = "system"
+
+	// ScopeManagedReq holds administrator-enforced settings that users
+	// cannot override. Added by ManagedScopes and WithManagedLayers.
+	ScopeManagedReq = "managed-required"
+
+	// ScopeManagedRec holds administrator-provided defaults that every
+	// other scope overrides. Added by ManagedScopes and WithManagedLayers.
+	ScopeManagedRec = "managed-recommended"
+)
+
+// appPlaceholder is replaced with the app name in scope subdirs and patterns.
+const appPlaceholder = "{app}"
+
+// CascadeConfig controls how a Cascade resolves scopes.
+type CascadeConfig struct {
+	// AppName is required. Used for directory names within each scope.
+	AppName string
+
+	// Dirs provides platform directory resolution for the user and system
+	// scopes. If nil, a PlatformDirs with default configuration is created.
+	Dirs Dirs
+
+	// Scopes defines the cascade hierarchy. If nil, uses DefaultScopes().
+	// Scopes are searched in priority order (lowest priority number first).
+	Scopes []ScopeConfig
+
+	// ProjectMarkers define filesystem entries that indicate a project root.
+	// Defaults to VCS markers (.git, .hg, .svn, .bzr) plus app-specific
+	// directories (.{app}, .config/{app}).
+	ProjectMarkers []string
+
+	// ConfigDirPatterns enable config-dir-aware project detection.
+	// When the working directory is inside a matching pattern, the directory
+	// containing the pattern is the project root; traversal does not climb
+	// further. Defaults to [".{app}", ".config/{app}"].
+	ConfigDirPatterns []string
+
+	// StopMarkers halt upward traversal during project detection.
+	// Useful for monorepo boundaries. Defaults to empty (traverse to root).
+	StopMarkers []string
+
+	// ProjectDirs defines directory types within the project.
+	// If nil, uses DefaultProjectDirs(AppName).
+	ProjectDirs map[string]ProjectDirConfig
+
+	// ProjectFiles defines known files with their variants and locations.
+	// If nil, no known files are configured.
+	ProjectFiles map[string]ProjectFileConfig
+
+	// FileVariants controls which naming patterns to check when resolving files.
+	// Defaults to VariantBoth (checks both "file.yaml" and ".file.yaml").
+	FileVariants VariantStyle
+
+	// FileResolver generates filenames when FileVariants is VariantCustom.
+	FileResolver FileResolver
+
+	// DisableProjectDetection turns off project root detection. Scopes that
+	// depend on a project root are skipped for reads and reject writes.
+	DisableProjectDetection bool
+
+	// Getwd provides the current working directory. If nil, uses os.Getwd.
+	// Useful for testing.
+	Getwd func() (string, error)
+}
+
+// ScopeConfig describes one layer of the cascade.
+type ScopeConfig struct {
+	// Name identifies this scope (e.g., "user", "project", "system").
+	Name string
+
+	// Priority determines search order. Lower numbers = higher priority.
+	// Reads check scopes in ascending priority order.
+	Priority int
+
+	// Writable indicates whether this scope accepts writes.
+	// Managed/system scopes are typically read-only.
+	Writable bool
+
+	// BasePath returns the base directory for this scope.
+	// Receives the Cascade instance for access to Dirs and project root.
+	// Returning an empty path or ErrNoProjectRoot marks the scope as
+	// unavailable; reads skip it and writes fail.
+	BasePath func(c *Cascade) (string, error)
+
+	// Subdirs are checked within the base path. For example, a project
+	// scope might check both ".myapp" and ".config/myapp" subdirectories.
+	// If empty, files are resolved directly under BasePath.
+	// Supports the {app} placeholder for the app name.
+	Subdirs []string
+}
+
+// CascadeOption configures a Cascade created with NewCascade.
+type CascadeOption func(*CascadeConfig)
+
+// WithCascadeDirs provides a custom Dirs for platform directory resolution.
+func WithCascadeDirs(dirs Dirs) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.Dirs = dirs
+	}
+}
+
+// WithScopes replaces the default scope configuration.
+func WithScopes(scopes ...ScopeConfig) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.Scopes = scopes
+	}
+}
+
+// WithProjectMarkers sets custom project root markers.
+func WithProjectMarkers(markers ...string) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.ProjectMarkers = markers
+	}
+}
+
+// WithConfigDirPatterns sets patterns for config-dir-aware detection.
+func WithConfigDirPatterns(patterns ...string) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.ConfigDirPatterns = patterns
+	}
+}
+
+// WithStopMarkers sets markers that halt upward traversal.
+func WithStopMarkers(markers ...string) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.StopMarkers = markers
+	}
+}
+
+// WithProjectDirs configures project directory types.
+func WithProjectDirs(dirs map[string]ProjectDirConfig) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.ProjectDirs = dirs
+	}
+}
+
+// WithProjectFiles configures known project files.
+func WithProjectFiles(files map[string]ProjectFileConfig) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.ProjectFiles = files
+	}
+}
+
+// WithFileVariants controls file naming pattern matching.
+func WithFileVariants(style VariantStyle) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.FileVariants = style
+	}
+}
+
+// WithFileResolver provides custom file variant resolution.
+// It sets FileVariants to VariantCustom.
+func WithFileResolver(resolver FileResolver) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.FileVariants = VariantCustom
+		cfg.FileResolver = resolver
+	}
+}
+
+// WithManagedLayers adds managed/required and managed/recommended scopes
+// for enterprise policy support. The layers wrap the scopes configured so
+// far, or DefaultScopes if none were set.
+func WithManagedLayers() CascadeOption {
+	return func(cfg *CascadeConfig) {
+		base := cfg.Scopes
+		if base == nil {
+			base = DefaultScopes()
+		}
+		cfg.Scopes = withManagedLayers(base)
+	}
+}
+
+// WithGetwd provides a custom working directory function (for testing).
+func WithGetwd(fn func() (string, error)) CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.Getwd = fn
+	}
+}
+
+// WithoutProjectDetection disables project scope entirely.
+// Useful for system daemons or tools without a project concept.
+func WithoutProjectDetection() CascadeOption {
+	return func(cfg *CascadeConfig) {
+		cfg.DisableProjectDetection = true
+	}
+}
+
+// Cascade provides layered path resolution across multiple scopes.
+// Reads search scopes from highest to lowest priority; writes target an
+// explicit, writable scope.
+type Cascade struct {
+	cfg    CascadeConfig
+	dirs   Dirs
+	scopes []ScopeConfig
+
+	mu           sync.Mutex
+	projectRoots map[string]string // working directory -> detected root
+}
+
+// NewCascade creates a Cascade with the given app name and options.
+// Returns ErrAppNameRequired if appName is empty or whitespace-only.
+func NewCascade(appName string, opts ...CascadeOption) (*Cascade, error) {
+	cfg := CascadeConfig{AppName: appName}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return NewCascadeWithConfig(cfg)
+}
+
+// NewCascadeWithConfig creates a Cascade with custom configuration.
+// Returns ErrAppNameRequired if CascadeConfig.AppName is empty or whitespace-only.
+func NewCascadeWithConfig(cfg CascadeConfig) (*Cascade, error) {
+	if strings.TrimSpace(cfg.AppName) == "" {
+		return nil, ErrAppNameRequired
+	}
+
+	dirs := cfg.Dirs
+	if dirs == nil {
+		pd, err := New(cfg.AppName)
+		if err != nil {
+			return nil, err
+		}
+		dirs = pd
+	}
+
+	scopes := cfg.Scopes
+	if scopes == nil {
+		scopes = DefaultScopes()
+	}
+	scopes = slices.Clone(scopes)
+	slices.SortStableFunc(scopes, func(a, b ScopeConfig) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+
+	if cfg.ProjectMarkers == nil {
+		cfg.ProjectMarkers = []string{
+			".git", ".hg", ".svn", ".bzr",
+			"." + appPlaceholder, filepath.Join(".config", appPlaceholder),
+		}
+	}
+	if cfg.ConfigDirPatterns == nil {
+		cfg.ConfigDirPatterns = []string{"." + appPlaceholder, filepath.Join(".config", appPlaceholder)}
+	}
+	if cfg.ProjectDirs == nil {
+		cfg.ProjectDirs = DefaultProjectDirs(cfg.AppName)
+	}
+	if cfg.Getwd == nil {
+		cfg.Getwd = os.Getwd
+	}
+
+	return &Cascade{
+		cfg:          cfg,
+		dirs:         dirs,
+		scopes:       scopes,
+		projectRoots: make(map[string]string),
+	}, nil
+}
+
+// DefaultScopes returns the default scope configuration for CLI tools with
+// project support: local → project → user → system.
+func DefaultScopes() []ScopeConfig {
+	return []ScopeConfig{
+		{
+			Name:     ScopeLocal,
+			Priority: 10,
+			Writable: true,
+			BasePath: localBasePath,
+		},
+		{
+			Name:     ScopeProject,
+			Priority: 20,
+			Writable: true,
+			BasePath: projectBasePath,
+			Subdirs:  []string{filepath.Join(".config", appPlaceholder), "." + appPlaceholder},
+		},
+		{
+			Name:     ScopeUser,
+			Priority: 30,
+			Writable: true,
+			BasePath: userBasePath,
+		},
+		{
+			Name:     ScopeSystem,
+			Priority: 40,
+			Writable: false,
+			BasePath: systemBasePath,
+		},
+	}
+}
+
+// ManagedScopes returns DefaultScopes wrapped in enterprise policy layers:
+//
+//  1. managed-required (priority 0) - enforced, user cannot override
+//  2. local (priority 10)
+//  3. project (priority 20)
+//  4. user (priority 30)
+//  5. system (priority 40)
+//  6. managed-recommended (priority 100) - defaults, user can override
+func ManagedScopes() []ScopeConfig {
+	return withManagedLayers(DefaultScopes())
+}
+
+// withManagedLayers returns a copy of scopes with the managed layers added.
+func withManagedLayers(scopes []ScopeConfig) []ScopeConfig {
+	managed := []ScopeConfig{
+		{
+			Name:     ScopeManagedReq,
+			Priority: 0, // Highest priority, applied last
+			Writable: false,
+			BasePath: managedRequiredBasePath,
+		},
+	}
+	managed = append(managed, scopes...)
+	return append(managed, ScopeConfig{
+		Name:     ScopeManagedRec,
+		Priority: 100, // Lowest priority, applied first
+		Writable: false,
+		BasePath: managedRecommendedBasePath,
+	})
+}
+
+// localBasePath resolves to {project}/.{app}.local for git-ignored overrides.
+func localBasePath(c *Cascade) (string, error) {
+	root, err := c.requireProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "."+c.cfg.AppName+".local"), nil
+}
+
+// projectBasePath resolves to the project root. The project scope's
+// subdirs select .config/{app} or .{app} within it.
+func projectBasePath(c *Cascade) (string, error) {
+	return c.requireProjectRoot()
+}
+
+// userBasePath resolves to the user config directory.
+func userBasePath(c *Cascade) (string, error) {
+	return c.dirs.UserConfigDir(), nil
+}
+
+// systemBasePath resolves to the primary system config directory.
+func systemBasePath(c *Cascade) (string, error) {
+	return c.dirs.SystemConfigDir(), nil
+}
+
+// managedRequiredBasePath resolves to the managed-required policy directory,
+// or "" if the Dirs implementation has no managed directories.
+func managedRequiredBasePath(c *Cascade) (string, error) {
+	if md, ok := c.dirs.(ManagedDirs); ok {
+		return md.ManagedRequiredConfigDir(), nil
+	}
+	return "", nil
+}
+
+// managedRecommendedBasePath resolves to the managed-recommended policy
+// directory, or "" if the Dirs implementation has no managed directories.
+func managedRecommendedBasePath(c *Cascade) (string, error) {
+	if md, ok := c.dirs.(ManagedDirs); ok {
+		return md.ManagedRecommendedConfigDir(), nil
+	}
+	return "", nil
+}
+
+// Dirs returns the underlying platform directory resolver.
+func (c *Cascade) Dirs() Dirs {
+	return c.dirs
+}
+
+// AppName returns the configured app name.
+func (c *Cascade) AppName() string {
+	return c.cfg.AppName
+}
+
+// ---------------------------------------------------------------------
+// Scope access
+// ---------------------------------------------------------------------
+
+// Scopes returns all configured scopes in priority order.
+func (c *Cascade) Scopes() []ScopeConfig {
+	return slices.Clone(c.scopes)
+}
+
+// ScopeDir returns the base directory for the named scope.
+// Returns ErrScopeNotFound for unknown scopes, ErrNoProjectRoot for
+// project-relative scopes outside a project, and ErrScopeUnavailable for
+// scopes without a directory on this system.
+func (c *Cascade) ScopeDir(name string) (string, error) {
+	sc, err := c.scope(name)
+	if err != nil {
+		return "", err
+	}
+	return c.scopeBase(sc)
+}
+
+// JoinScope joins path elements to a scope's base directory.
+func (c *Cascade) JoinScope(scope string, elem ...string) (string, error) {
+	base, err := c.ScopeDir(scope)
+	if err != nil {
+		return "", err
+	}
+	return path(base, elem...), nil
+}
+
+// ---------------------------------------------------------------------
+// Cross-scope file resolution
+// ---------------------------------------------------------------------
+
+// ResolveFile finds a file across scopes. Returns the highest-priority
+// existing file, any additional existing files in other scopes, and an error.
+// If no file exists in any scope, primary is empty and alternates is nil.
+// With no scopes given, all configured scopes are searched.
+func (c *Cascade) ResolveFile(basename string, scopes ...string) (string, []string, error) {
+	return c.ResolveFileIn(basename, scopes)
+}
+
+// ResolveFileIn is like ResolveFile but searches only the specified scopes.
+func (c *Cascade) ResolveFileIn(basename string, scopes []string) (string, []string, error) {
+	candidates, err := c.candidatePaths(c.candidates(basename), scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	primary, alternates := firstAndRest(candidates, c.exists)
+	return primary, alternates, nil
+}
+
+// AllFilePaths returns all candidate paths for a file across scopes,
+// whether or not they exist. Useful for documentation or debugging.
+// Unavailable and unknown scopes are omitted.
+func (c *Cascade) AllFilePaths(basename string, scopes ...string) []string {
+	candidates, _ := c.candidatePaths(c.candidates(basename), scopes)
+	return candidates
+}
+
+// ExistingFiles returns paths to all existing instances of a file
+// across scopes, in priority order.
+func (c *Cascade) ExistingFiles(basename string, scopes ...string) []string {
+	var existing []string
+	for _, p := range c.AllFilePaths(basename, scopes...) {
+		if c.exists(p) {
+			existing = append(existing, p)
+		}
+	}
+	return existing
+}
+
+// ---------------------------------------------------------------------
+// Cross-scope directory resolution
+// ---------------------------------------------------------------------
+
+// ResolveDir finds a directory across scopes.
+func (c *Cascade) ResolveDir(basename string, scopes ...string) (string, []string, error) {
+	return c.ResolveDirIn(basename, scopes)
+}
+
+// ResolveDirIn is like ResolveDir but searches only the specified scopes.
+func (c *Cascade) ResolveDirIn(basename string, scopes []string) (string, []string, error) {
+	candidates, err := c.candidatePaths([]string{basename}, scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	primary, alternates := firstAndRest(candidates, c.isDir)
+	return primary, alternates, nil
+}
+
+// AllDirPaths returns all candidate paths for a directory across scopes.
+func (c *Cascade) AllDirPaths(basename string, scopes ...string) []string {
+	candidates, _ := c.candidatePaths([]string{basename}, scopes)
+	return candidates
+}
+
+// ExistingDirs returns paths to all existing instances of a directory.
+func (c *Cascade) ExistingDirs(basename string, scopes ...string) []string {
+	var existing []string
+	for _, p := range c.AllDirPaths(basename, scopes...) {
+		if c.isDir(p) {
+			existing = append(existing, p)
+		}
+	}
+	return existing
+}
+
+// ---------------------------------------------------------------------
+// Write targeting
+// ---------------------------------------------------------------------
+
+// DefaultPath returns the path where a new file should be written
+// in the specified scope. Does not check existence. The filename is the
+// first variant produced for basename. Returns ErrScopeNotWritable if the
+// scope does not accept writes.
+func (c *Cascade) DefaultPath(basename, scope string) (string, error) {
+	dir, err := c.writeDir(scope)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, c.defaultName(basename)), nil
+}
+
+// DefaultDir returns the directory path in the specified scope.
+func (c *Cascade) DefaultDir(basename, scope string) (string, error) {
+	dir, err := c.writeDir(scope)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, basename), nil
+}
+
+// writeDir returns the first directory of a writable scope.
+func (c *Cascade) writeDir(name string) (string, error) {
+	sc, err := c.scope(name)
+	if err != nil {
+		return "", err
+	}
+	if !sc.Writable {
+		return "", fmt.Errorf("%w: %s", ErrScopeNotWritable, name)
+	}
+	dirs, err := c.scopeDirs(sc)
+	if err != nil {
+		return "", err
+	}
+	return dirs[0], nil
+}
+
+// ---------------------------------------------------------------------
+// Internal: scope helpers
+// ---------------------------------------------------------------------
+
+// scope looks up a configured scope by name.
+func (c *Cascade) scope(name string) (ScopeConfig, error) {
+	for _, sc := range c.scopes {
+		if sc.Name == name {
+			return sc, nil
+		}
+	}
+	return ScopeConfig{}, fmt.Errorf("%w: %s", ErrScopeNotFound, name)
+}
+
+// selectScopes returns the named scopes in priority order, or all scopes
+// if names is empty.
+func (c *Cascade) selectScopes(names []string) ([]ScopeConfig, error) {
+	if len(names) == 0 {
+		return c.scopes, nil
+	}
+	for _, name := range names {
+		if _, err := c.scope(name); err != nil {
+			return nil, err
+		}
+	}
+	var selected []ScopeConfig
+	for _, sc := range c.scopes {
+		if slices.Contains(names, sc.Name) {
+			selected = append(selected, sc)
+		}
+	}
+	return selected, nil
+}
+
+// scopeBase resolves a scope's base path, reporting ErrScopeUnavailable
+// when the scope resolves to nothing.
+func (c *Cascade) scopeBase(sc ScopeConfig) (string, error) {
+	if sc.BasePath == nil {
+		return "", fmt.Errorf("%w: %s has no BasePath", ErrScopeUnavailable, sc.Name)
+	}
+	base, err := sc.BasePath(c)
+	if err != nil {
+		return "", err
+	}
+	if base == "" {
+		return "", fmt.Errorf("%w: %s", ErrScopeUnavailable, sc.Name)
+	}
+	return base, nil
+}
+
+// scopeDirs returns the directories searched within a scope: each subdir
+// under the base path, or the base path itself if there are no subdirs.
+func (c *Cascade) scopeDirs(sc ScopeConfig) ([]string, error) {
+	base, err := c.scopeBase(sc)
+	if err != nil {
+		return nil, err
+	}
+	return c.joinSubdirs(sc, base), nil
+}
+
+// joinSubdirs joins each of the scope's subdirs onto base.
+func (c *Cascade) joinSubdirs(sc ScopeConfig, base string) []string {
+	if len(sc.Subdirs) == 0 {
+		return []string{base}
+	}
+	dirs := make([]string, 0, len(sc.Subdirs))
+	for _, sub := range c.expandAll(sc.Subdirs) {
+		dirs = append(dirs, filepath.Join(base, sub))
+	}
+	return dirs
+}
+
+// candidatePaths returns every path to check for filenames across the
+// selected scopes, in priority order: scope, then subdir, then filename.
+// Scopes without a project root or with an empty base path are skipped.
+func (c *Cascade) candidatePaths(filenames, names []string) ([]string, error) {
+	scopes, err := c.selectScopes(names)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, sc := range scopes {
+		if sc.BasePath == nil {
+			continue
+		}
+		base, baseErr := sc.BasePath(c)
+		if errors.Is(baseErr, ErrNoProjectRoot) || (baseErr == nil && base == "") {
+			continue
+		}
+		if baseErr != nil {
+			return nil, baseErr
+		}
+		for _, dir := range c.joinSubdirs(sc, base) {
+			for _, name := range filenames {
+				paths = append(paths, filepath.Join(dir, name))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// firstAndRest splits the candidates that satisfy ok into a primary match
+// and alternates.
+func firstAndRest(candidates []string, ok func(string) bool) (string, []string) {
+	var primary string
+	var alternates []string
+	for _, p := range candidates {
+		if !ok(p) {
+			continue
+		}
+		if primary == "" {
+			primary = p
+		} else {
+			alternates = append(alternates, p)
+		}
+	}
+	return primary, alternates
+}
+
+// candidates returns the filenames to check for basename according to the
+// configured variant style or resolver.
+func (c *Cascade) candidates(basename string) []string {
+	if c.cfg.FileVariants == VariantCustom && c.cfg.FileResolver != nil {
+		return c.cfg.FileResolver.Candidates(basename)
+	}
+	return c.cfg.FileVariants.Candidates(basename)
+}
+
+// defaultName returns the filename used when writing basename: the first
+// candidate, or basename itself if the resolver produces none.
+func (c *Cascade) defaultName(basename string) string {
+	if names := c.candidates(basename); len(names) > 0 {
+		return names[0]
+	}
+	return basename
+}
+
+// expand replaces the {app} placeholder with the app name and converts
+// slashes to the platform separator.
+func (c *Cascade) expand(pattern string) string {
+	return filepath.FromSlash(strings.ReplaceAll(pattern, appPlaceholder, c.cfg.AppName))
+}
+
+// expandAll applies expand to each pattern.
+func (c *Cascade) expandAll(patterns []string) []string {
+	expanded := make([]string, len(patterns))
+	for i, p := range patterns {
+		expanded[i] = c.expand(p)
+	}
+	return expanded
+}
+
+// ---------------------------------------------------------------------
+// Internal: existence checks
+// ---------------------------------------------------------------------
+
+// existenceChecker is implemented by Dirs types that decide for themselves
+// which paths exist: FakeDirs, and PlatformDirs through its FS.
+type existenceChecker interface {
+	fileExists(path string) bool
+	dirExists(path string) bool
+}
+
+// exists reports whether path exists, deferring to the Dirs implementation
+// when it controls existence.
+func (c *Cascade) exists(p string) bool {
+	if ec, ok := c.dirs.(existenceChecker); ok {
+		return ec.fileExists(p)
+	}
+	return fileExists(p)
+}
+
+// isDir reports whether path is an existing directory, deferring to the
+// Dirs implementation when it controls existence.
+func (c *Cascade) isDir(p string) bool {
+	if ec, ok := c.dirs.(existenceChecker); ok {
+		return ec.dirExists(p)
+	}
+	return dirExists(p)
+}
@@ -0,0 +1,334 @@
+package toolpaths_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// newTestCascade creates a Cascade backed by FakeDirs rooted at base, with
+// the working directory fixed to cwd.
+func newTestCascade(
+	t *testing.T,
+	base, cwd string,
+	opts ...toolpaths.CascadeOption,
+) (*toolpaths.Cascade, *toolpaths.FakeDirs) {
+	t.Helper()
+	fake := toolpaths.NewFakeDirs(base)
+	opts = append([]toolpaths.CascadeOption{
+		toolpaths.WithCascadeDirs(fake),
+		toolpaths.WithGetwd(func() (string, error) { return cwd, nil }),
+	}, opts...)
+	c, err := toolpaths.NewCascade("myapp", opts...)
+	require.NoError(t, err)
+	return c, fake
+}
+
+func TestNewCascadeAppNameRequired(t *testing.T) {
+	c, err := toolpaths.NewCascade("  ")
+	require.ErrorIs(t, err, toolpaths.ErrAppNameRequired)
+	assert.Nil(t, c)
+}
+
+func TestNewCascadeDefaults(t *testing.T) {
+	c, err := toolpaths.NewCascade("myapp")
+	require.NoError(t, err)
+	assert.NotNil(t, c.Dirs())
+	assert.Equal(t, "myapp", c.AppName())
+
+	var names []string
+	for _, sc := range c.Scopes() {
+		names = append(names, sc.Name)
+	}
+	assert.Equal(t, []string{"local", "project", "user", "system"}, names)
+}
+
+func TestCascadeScopesSortedByPriority(t *testing.T) {
+	base := testBase()
+	c, _ := newTestCascade(t, base, base, toolpaths.WithScopes(
+		toolpaths.ScopeConfig{Name: "b", Priority: 20},
+		toolpaths.ScopeConfig{Name: "max", Priority: math.MaxInt},
+		toolpaths.ScopeConfig{Name: "a", Priority: 10},
+		toolpaths.ScopeConfig{Name: "min", Priority: math.MinInt},
+	))
+
+	var names []string
+	for _, sc := range c.Scopes() {
+		names = append(names, sc.Name)
+	}
+	assert.Equal(t, []string{"min", "a", "b", "max"}, names, "extreme priorities do not overflow")
+}
+
+func TestCascadeProjectRoot(t *testing.T) {
+	base := testBase()
+	project := p(base, "work", "proj")
+
+	t.Run("detects VCS marker", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, p(project, "src"))
+		fake.SetExisting(p(project, ".git"))
+
+		root, err := c.ProjectRoot()
+		require.NoError(t, err)
+		assert.Equal(t, project, root)
+		assert.True(t, c.IsInsideProject())
+	})
+
+	t.Run("returns empty outside a project", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, p(project, "src"))
+
+		root, err := c.ProjectRoot()
+		require.NoError(t, err)
+		assert.Empty(t, root)
+		assert.False(t, c.IsInsideProject())
+	})
+
+	t.Run("stop markers bound traversal", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, p(project, "src"),
+			toolpaths.WithStopMarkers("pnpm-workspace.yaml"))
+		fake.SetExisting(p(base, "work", ".git"))
+		fake.SetExisting(p(project, "pnpm-workspace.yaml"))
+
+		root, err := c.ProjectRoot()
+		require.NoError(t, err)
+		assert.Empty(t, root)
+	})
+
+	t.Run("disabled detection", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, toolpaths.WithoutProjectDetection())
+		fake.SetExisting(p(project, ".git"))
+
+		root, err := c.ProjectRoot()
+		require.NoError(t, err)
+		assert.Empty(t, root)
+	})
+
+	t.Run("getwd error", func(t *testing.T) {
+		wantErr := errors.New("no cwd")
+		c, _ := newTestCascade(t, base, project,
+			toolpaths.WithGetwd(func() (string, error) { return "", wantErr }))
+
+		_, err := c.ProjectRoot()
+		require.ErrorIs(t, err, wantErr)
+	})
+}
+
+func TestCascadeScopeDir(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	c, fake := newTestCascade(t, base, project)
+	fake.SetExisting(p(project, ".git"))
+
+	tests := []struct {
+		scope string
+		want  string
+	}{
+		{"local", p(project, ".myapp.local")},
+		{"project", project},
+		{"user", p(base, "config")},
+		{"system", p(base, "system", "config")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			dir, err := c.ScopeDir(tt.scope)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, dir)
+		})
+	}
+
+	_, err := c.ScopeDir("nope")
+	require.ErrorIs(t, err, toolpaths.ErrScopeNotFound)
+
+	t.Run("unavailable", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, project, toolpaths.WithScopes(
+			toolpaths.ScopeConfig{Name: "nobase"},
+			toolpaths.ScopeConfig{Name: "empty", BasePath: func(*toolpaths.Cascade) (string, error) { return "", nil }},
+		))
+		for _, name := range []string{"nobase", "empty"} {
+			_, err := c.ScopeDir(name)
+			require.ErrorIs(t, err, toolpaths.ErrScopeUnavailable, name)
+		}
+	})
+}
+
+func TestCascadeResolveFile(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+
+	t.Run("highest priority scope wins", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project)
+		fake.SetExisting(p(project, ".git"))
+		fake.SetExisting(p(project, ".myapp", "config.yaml"))
+		fake.SetExisting(p(base, "config", "config.yaml"))
+		fake.SetExisting(p(base, "system", "config", "config.yaml"))
+
+		primary, alternates, err := c.ResolveFile("config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".myapp", "config.yaml"), primary)
+		assert.Equal(t, []string{
+			p(base, "config", "config.yaml"),
+			p(base, "system", "config", "config.yaml"),
+		}, alternates)
+	})
+
+	t.Run("project subdirs checked in order", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project)
+		fake.SetExisting(p(project, ".git"))
+		fake.SetExisting(p(project, ".myapp", "config.yaml"))
+		fake.SetExisting(p(project, ".config", "myapp", "config.yaml"))
+
+		primary, alternates, err := c.ResolveFile("config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".config", "myapp", "config.yaml"), primary)
+		assert.Equal(t, []string{p(project, ".myapp", "config.yaml")}, alternates)
+	})
+
+	t.Run("project scopes skipped outside a project", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project)
+		fake.SetExisting(p(base, "config", "config.yaml"))
+
+		primary, alternates, err := c.ResolveFile("config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "config", "config.yaml"), primary)
+		assert.Empty(t, alternates)
+	})
+
+	t.Run("restricted to named scopes", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project)
+		fake.SetExisting(p(base, "config", "config.yaml"))
+		fake.SetExisting(p(base, "system", "config", "config.yaml"))
+
+		primary, alternates, err := c.ResolveFileIn("config.yaml", []string{"system"})
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "system", "config", "config.yaml"), primary)
+		assert.Empty(t, alternates)
+	})
+
+	t.Run("nothing found", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, project)
+
+		primary, alternates, err := c.ResolveFile("config.yaml")
+		require.NoError(t, err)
+		assert.Empty(t, primary)
+		assert.Nil(t, alternates)
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, project)
+
+		_, _, err := c.ResolveFile("config.yaml", "nope")
+		require.ErrorIs(t, err, toolpaths.ErrScopeNotFound)
+	})
+}
+
+func TestCascadeAllAndExistingFiles(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	c, fake := newTestCascade(t, base, project, toolpaths.WithFileVariants(toolpaths.VariantPlain))
+	fake.SetExisting(p(project, ".git"))
+	fake.SetExisting(p(project, ".myapp.local", "config.yaml"))
+	fake.SetExisting(p(base, "config", "config.yaml"))
+
+	assert.Equal(t, []string{
+		p(project, ".myapp.local", "config.yaml"),
+		p(project, ".config", "myapp", "config.yaml"),
+		p(project, ".myapp", "config.yaml"),
+		p(base, "config", "config.yaml"),
+		p(base, "system", "config", "config.yaml"),
+	}, c.AllFilePaths("config.yaml"))
+
+	assert.Equal(t, []string{
+		p(project, ".myapp.local", "config.yaml"),
+		p(base, "config", "config.yaml"),
+	}, c.ExistingFiles("config.yaml"))
+
+	assert.Equal(t, []string{p(base, "config", "config.yaml")},
+		c.ExistingFiles("config.yaml", "user", "system"))
+}
+
+func TestCascadeResolveDir(t *testing.T) {
+	base := t.TempDir()
+	project := filepath.Join(base, "proj")
+	userDir := filepath.Join(base, "user")
+	require.NoError(t, os.MkdirAll(filepath.Join(project, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(project, ".myapp", "hooks"), 0o755))
+	require.NoError(t, os.MkdirAll(userDir, 0o755))
+	// A file named like the directory must not match.
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "hooks"), nil, 0o644))
+
+	fake := toolpaths.NewFakeDirs(base)
+	fake.ExistingFiles = nil // use the real filesystem
+	fake.UserConfigHomeVal = userDir
+
+	c, err := toolpaths.NewCascade("myapp",
+		toolpaths.WithCascadeDirs(fake),
+		toolpaths.WithGetwd(func() (string, error) { return project, nil }),
+	)
+	require.NoError(t, err)
+
+	primary, alternates, err := c.ResolveDir("hooks")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(project, ".myapp", "hooks"), primary)
+	assert.Empty(t, alternates)
+	assert.Equal(t, []string{filepath.Join(project, ".myapp", "hooks")}, c.ExistingDirs("hooks"))
+}
+
+func TestCascadeDefaultPath(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	c, fake := newTestCascade(t, base, project)
+	fake.SetExisting(p(project, ".git"))
+
+	t.Run("user scope", func(t *testing.T) {
+		got, err := c.DefaultPath("config.yaml", "user")
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "config", "config.yaml"), got)
+	})
+
+	t.Run("project scope uses first subdir", func(t *testing.T) {
+		got, err := c.DefaultPath("config.yaml", "project")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".config", "myapp", "config.yaml"), got)
+	})
+
+	t.Run("read-only scope", func(t *testing.T) {
+		_, err := c.DefaultPath("config.yaml", "system")
+		require.ErrorIs(t, err, toolpaths.ErrScopeNotWritable)
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		_, err := c.DefaultDir("hooks", "nope")
+		require.ErrorIs(t, err, toolpaths.ErrScopeNotFound)
+	})
+
+	t.Run("project scope outside a project", func(t *testing.T) {
+		outside, _ := newTestCascade(t, base, project)
+		_, err := outside.DefaultPath("config.yaml", "project")
+		require.ErrorIs(t, err, toolpaths.ErrNoProjectRoot)
+	})
+}
+
+func TestCascadeJoin(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	c, fake := newTestCascade(t, base, project)
+	fake.SetExisting(p(project, ".git"))
+
+	got, err := c.JoinScope("user", "themes", "dark.json")
+	require.NoError(t, err)
+	assert.Equal(t, p(base, "config", "themes", "dark.json"), got)
+
+	got, err = c.JoinProject("build", "out")
+	require.NoError(t, err)
+	assert.Equal(t, p(project, "build", "out"), got)
+
+	outside, _ := newTestCascade(t, base, project)
+	_, err = outside.JoinProject("build")
+	require.ErrorIs(t, err, toolpaths.ErrNoProjectRoot)
+}
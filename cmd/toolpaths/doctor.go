@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// Finding severities.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// finding is one problem reported by the doctor command.
+type finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Kind     string `json:"kind,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// report is the JSON form of the doctor output.
+type report struct {
+	App      string    `json:"app"`
+	Platform string    `json:"platform"`
+	Findings []finding `json:"findings"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+}
+
+// runDoctor audits every resolved directory and prints the findings.
+// It returns exitError if any error was found, or any warning when strict.
+func runDoctor(dirs *toolpaths.PlatformDirs, opts options, stdout io.Writer) (int, error) {
+	diag := dirs.Diagnose()
+	r := report{
+		App:      diag.AppName,
+		Platform: diag.Platform.String(),
+		Findings: audit(dirs, diag),
+	}
+	for _, f := range r.Findings {
+		if f.Severity == severityError {
+			r.Errors++
+		} else {
+			r.Warnings++
+		}
+	}
+
+	var err error
+	if opts.format == formatJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		err = writeReport(stdout, r)
+	}
+	if err != nil {
+		return exitError, err
+	}
+
+	if r.Errors > 0 || (opts.strict && r.Warnings > 0) {
+		return exitError, nil
+	}
+	return exitOK, nil
+}
+
+// audit runs every check.
+func audit(dirs *toolpaths.PlatformDirs, diag toolpaths.Diagnostics) []finding {
+	findings := []finding{}
+	findings = append(findings, checkHome()...)
+	findings = append(findings, checkRelativeXDG()...)
+	findings = append(findings, checkRuntime(dirs, diag)...)
+	findings = append(findings, checkWorldWritable(diag)...)
+	findings = append(findings, checkStaleFallbacks(dirs, diag)...)
+	return findings
+}
+
+// checkHome reports a home directory that is unset or does not exist.
+func checkHome() []finding {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return []finding{{
+			Check:    "home-missing",
+			Severity: severityError,
+			Message:  fmt.Sprintf("home directory is not set: %v", err),
+		}}
+	}
+	if info, err := os.Stat(home); err != nil || !info.IsDir() {
+		return []finding{{
+			Check:    "home-missing",
+			Severity: severityError,
+			Path:     home,
+			Message:  "home directory does not exist",
+		}}
+	}
+	return nil
+}
+
+// checkRelativeXDG reports relative paths in XDG variables. The XDG spec
+// requires relative paths to be ignored, but they are used as-is here and
+// resolve against the working directory.
+func checkRelativeXDG() []finding {
+	var findings []finding
+	for _, name := range []string{
+		"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME", "XDG_RUNTIME_DIR",
+		"XDG_CONFIG_DIRS", "XDG_DATA_DIRS",
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		entries := []string{value}
+		if strings.HasSuffix(name, "_DIRS") {
+			entries = strings.Split(value, ":")
+		}
+		for _, entry := range entries {
+			if entry != "" && !filepath.IsAbs(entry) {
+				findings = append(findings, finding{
+					Check:    "relative-xdg-path",
+					Severity: severityError,
+					Path:     entry,
+					Message:  fmt.Sprintf("%s contains a relative path; it resolves against the working directory", name),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// checkRuntime reports the temp-dir runtime fallback and runtime directories
+// that are not private to the current user.
+func checkRuntime(dirs *toolpaths.PlatformDirs, diag toolpaths.Diagnostics) []finding {
+	var findings []finding
+
+	if x, err := dirs.Explain("user-runtime"); err == nil {
+		if step, ok := x.Chosen(); ok && step.Strategy == toolpaths.StrategyTempDir &&
+			diag.Platform != toolpaths.PlatformMacOS {
+			findings = append(findings, finding{
+				Check:    "runtime-fallback",
+				Severity: severityWarning,
+				Kind:     x.Kind,
+				Path:     x.Path,
+				Message:  "XDG_RUNTIME_DIR is not set; using a temp directory that persists across logins",
+			})
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return findings
+	}
+	for _, info := range diag.Dirs {
+		if info.Kind != "user-runtime" || !info.Exists {
+			continue
+		}
+		if uid := os.Getuid(); info.UID >= 0 && info.UID != uid {
+			findings = append(findings, finding{
+				Check:    "runtime-owner",
+				Severity: severityError,
+				Kind:     info.Kind,
+				Path:     info.Path,
+				Message:  fmt.Sprintf("owned by uid %d, not the current user (uid %d)", info.UID, uid),
+			})
+		}
+		if perm := info.Mode.Perm(); perm != 0o700 {
+			findings = append(findings, finding{
+				Check:    "runtime-mode",
+				Severity: severityError,
+				Kind:     info.Kind,
+				Path:     info.Path,
+				Message:  fmt.Sprintf("mode %04o, want 0700", perm),
+			})
+		}
+	}
+	return findings
+}
+
+// checkWorldWritable reports config directories anyone can write to.
+func checkWorldWritable(diag toolpaths.Diagnostics) []finding {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	var findings []finding
+	for _, info := range diag.Dirs {
+		if !info.Exists || !isConfigKind(info.Kind) {
+			continue
+		}
+		if info.Mode.Perm()&0o002 != 0 {
+			findings = append(findings, finding{
+				Check:    "world-writable",
+				Severity: severityError,
+				Kind:     info.Kind,
+				Path:     info.Path,
+				Message:  fmt.Sprintf("config directory is world-writable (mode %04o)", info.Mode.Perm()),
+			})
+		}
+	}
+	return findings
+}
+
+func isConfigKind(kind string) bool {
+	return strings.HasSuffix(kind, "-config") || strings.HasPrefix(kind, "managed-")
+}
+
+// staleChecks maps each read-only search strategy to the check name and
+// message reported when its directory still holds entries.
+var staleChecks = map[toolpaths.Strategy]struct{ check, message string }{
+	toolpaths.StrategyXDGFallback: {
+		"stale-fallback", "XDG fallback location holds %d entries; migrate them to the primary directory",
+	},
+	toolpaths.StrategyPreviousVersion: {
+		"stale-previous-version", "previous version's directory holds %d entries; migrate them, then remove it",
+	},
+	toolpaths.StrategyLegacy: {
+		"stale-legacy", "legacy location holds %d entries; migrate them to the primary directory",
+	},
+}
+
+// checkStaleFallbacks reports non-empty read-only search locations: XDG
+// fallback directories on macOS and Windows, the previous version's
+// directories, and legacy locations. Each usually holds data left over
+// from before a migration to the primary directory.
+func checkStaleFallbacks(dirs *toolpaths.PlatformDirs, diag toolpaths.Diagnostics) []finding {
+	var findings []finding
+	for _, info := range diag.Dirs {
+		if info.Primary || !info.Exists || !strings.HasPrefix(info.Kind, "user-") {
+			continue
+		}
+		strategy := searchStrategy(dirs, info)
+		if strategy == toolpaths.StrategyXDGFallback &&
+			diag.Platform != toolpaths.PlatformMacOS && diag.Platform != toolpaths.PlatformWindows {
+			continue
+		}
+		stale, ok := staleChecks[strategy]
+		if !ok {
+			continue
+		}
+		entries, err := os.ReadDir(info.Path)
+		if err != nil || len(entries) == 0 {
+			continue
+		}
+		findings = append(findings, finding{
+			Check:    stale.check,
+			Severity: severityWarning,
+			Kind:     info.Kind,
+			Path:     info.Path,
+			Message:  fmt.Sprintf(stale.message, len(entries)),
+		})
+	}
+	return findings
+}
+
+// searchStrategy returns the strategy that listed info.Path among the
+// search locations for info.Kind, or "" if Explain does not name one.
+func searchStrategy(dirs *toolpaths.PlatformDirs, info toolpaths.DirInfo) toolpaths.Strategy {
+	x, err := dirs.Explain(info.Kind)
+	if err != nil {
+		return ""
+	}
+	for _, step := range x.Steps {
+		if step.Path == info.Path && !step.Chosen {
+			return step.Strategy
+		}
+	}
+	return ""
+}
+
+func writeReport(w io.Writer, r report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "toolpaths doctor for %q (%s)\n", r.App, r.Platform)
+	if len(r.Findings) == 0 {
+		b.WriteString("No problems found.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	b.WriteString("\n")
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "%-7s  %s", strings.ToUpper(f.Severity), f.Check)
+		if f.Kind != "" {
+			fmt.Fprintf(&b, " [%s]", f.Kind)
+		}
+		b.WriteString("\n")
+		if f.Path != "" {
+			fmt.Fprintf(&b, "         %s\n", f.Path)
+		}
+		fmt.Fprintf(&b, "         %s\n", f.Message)
+	}
+	fmt.Fprintf(&b, "\n%d error(s), %d warning(s)\n", r.Errors, r.Warnings)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
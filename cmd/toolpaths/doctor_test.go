@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// setCleanEnv points HOME and the XDG variables at a fresh temp directory
+// so the doctor finds no problems by default.
+func setCleanEnv(t *testing.T) string {
+	t.Helper()
+	base := t.TempDir()
+	t.Setenv("HOME", base)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(base, "config"))
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(base, "run"))
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(base, "etc"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(base, "share"))
+	return base
+}
+
+func runDoctorJSON(t *testing.T, args ...string) (int, report) {
+	t.Helper()
+	args = append([]string{"--app", "myapp", "--format", "json"}, args...)
+	code, stdout, stderr := runCmd(t, append(args, "doctor")...)
+	var r report
+	require.NoError(t, json.Unmarshal([]byte(stdout), &r), stderr)
+	return code, r
+}
+
+func checks(r report) []string {
+	var names []string
+	for _, f := range r.Findings {
+		names = append(names, f.Check)
+	}
+	return names
+}
+
+func TestDoctorClean(t *testing.T) {
+	setCleanEnv(t)
+
+	code, stdout, _ := runCmd(t, "--app", "myapp", "--platform", "linux", "doctor")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "No problems found.")
+
+	code, r := runDoctorJSON(t, "--platform", "linux")
+	assert.Equal(t, exitOK, code)
+	assert.Empty(t, r.Findings)
+	assert.Equal(t, "linux", r.Platform)
+}
+
+func TestDoctorRuntimeFallback(t *testing.T) {
+	setCleanEnv(t)
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	code, r := runDoctorJSON(t, "--platform", "linux")
+	assert.Equal(t, exitOK, code, "warnings alone do not fail")
+	assert.Equal(t, []string{"runtime-fallback"}, checks(r))
+	assert.Equal(t, 1, r.Warnings)
+
+	code, _ = runDoctorJSON(t, "--platform", "linux", "--strict")
+	assert.Equal(t, exitError, code)
+}
+
+func TestDoctorRelativeXDG(t *testing.T) {
+	setCleanEnv(t)
+	t.Setenv("XDG_CONFIG_DIRS", "relative/etc")
+
+	code, r := runDoctorJSON(t, "--platform", "linux")
+	assert.Equal(t, exitError, code)
+	require.Equal(t, []string{"relative-xdg-path"}, checks(r))
+	assert.Equal(t, "relative/etc", r.Findings[0].Path)
+}
+
+func TestDoctorRuntimePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions only")
+	}
+	base := setCleanEnv(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "run", "myapp"), 0o755))
+	require.NoError(t, os.Chmod(filepath.Join(base, "run", "myapp"), 0o755))
+
+	code, r := runDoctorJSON(t, "--platform", "linux")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, []string{"runtime-mode"}, checks(r))
+}
+
+func TestDoctorWorldWritableConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions only")
+	}
+	base := setCleanEnv(t)
+	dir := filepath.Join(base, "config", "myapp")
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	require.NoError(t, os.Chmod(dir, 0o777))
+
+	code, r := runDoctorJSON(t, "--platform", "linux")
+	assert.Equal(t, exitError, code)
+	require.Equal(t, []string{"world-writable"}, checks(r))
+	assert.Equal(t, "user-config", r.Findings[0].Kind)
+}
+
+func TestDoctorStaleFallback(t *testing.T) {
+	base := setCleanEnv(t)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	stale := filepath.Join(base, ".config", "myapp")
+	require.NoError(t, os.MkdirAll(stale, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(stale, "config.yaml"), nil, 0o600))
+
+	code, r := runDoctorJSON(t, "--platform", "macos")
+	assert.Equal(t, exitOK, code)
+	require.Equal(t, []string{"stale-fallback"}, checks(r))
+	assert.Equal(t, stale, r.Findings[0].Path)
+}
+
+func TestDoctorStalePreviousVersionAndLegacy(t *testing.T) {
+	base := setCleanEnv(t)
+	previous := filepath.Join(base, "config", "myapp", "1")
+	legacy := filepath.Join(base, ".myapp")
+	for _, dir := range []string{previous, legacy} {
+		require.NoError(t, os.MkdirAll(dir, 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), nil, 0o600))
+	}
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:         "myapp",
+		Platform:        toolpaths.PlatformLinux,
+		Version:         "2",
+		VersionFallback: true,
+		LegacyPaths:     &toolpaths.LegacyPaths{UserConfig: []string{"~/.myapp"}},
+	})
+	require.NoError(t, err)
+
+	findings := checkStaleFallbacks(dirs, dirs.Diagnose())
+	require.Len(t, findings, 2)
+	assert.Equal(t, "stale-previous-version", findings[0].Check)
+	assert.Equal(t, previous, findings[0].Path)
+	assert.Equal(t, "stale-legacy", findings[1].Check)
+	assert.Equal(t, legacy, findings[1].Path)
+}
+
+func TestDoctorMissingHome(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("HOME is not used on Windows")
+	}
+	base := setCleanEnv(t)
+	t.Setenv("HOME", filepath.Join(base, "nope"))
+
+	code, stdout, _ := runCmd(t, "--app", "myapp", "--platform", "linux", "doctor")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stdout, "home-missing")
+	assert.Contains(t, stdout, "1 error(s), 0 warning(s)")
+}
+
+func TestDoctorUsage(t *testing.T) {
+	code, _, stderr := runCmd(t, "--app", "myapp", "doctor", "extra")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "doctor takes no arguments")
+
+	code, _, stderr = runCmd(t, "--app", "myapp", "--format", "shell", "doctor")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "text and json")
+}
@@ -0,0 +1,342 @@
+// Command toolpaths prints the directories an application resolves with
+// the toolpaths library, so shell scripts, Makefiles, and install hooks
+// get the same answers as Go binaries.
+//
+// Usage:
+//
+//	toolpaths --app NAME [flags] [kind...]
+//	toolpaths --app NAME [flags] doctor
+//
+// Kinds are the names reported by toolpaths.Kinds (user-config,
+// system-data, ...). The user directory kinds may be abbreviated to
+// config, data, cache, state, log, and runtime. With no kinds, every
+// available directory is printed.
+//
+// The doctor subcommand audits the resolved directories for problems
+// (missing HOME, the XDG_RUNTIME_DIR fallback, runtime directories not
+// private to the user, world-writable config directories, relative XDG
+// paths, and stale data in XDG fallback, previous-version, and legacy
+// locations). It exits non-zero if it finds an error, or any problem with
+// --strict.
+//
+// Examples:
+//
+//	toolpaths --app myapp config
+//	toolpaths --app myapp --platform windows --author Acme --roaming config
+//	toolpaths --app myapp --format json
+//	eval "$(toolpaths --app myapp --format shell)"
+//	toolpaths --app myapp --format json doctor
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// Output formats.
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatShell = "shell"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// options holds parsed command-line flags.
+type options struct {
+	cfg    toolpaths.Config
+	format string
+	prefix string
+	strict bool
+	doctor bool
+	kinds  []string
+}
+
+// run executes the command and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "toolpaths: %v\n", err)
+		return exitUsage
+	}
+
+	dirs, err := toolpaths.NewWithConfig(opts.cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "toolpaths: %v\n", err)
+		return exitUsage
+	}
+
+	if opts.doctor {
+		code, err := runDoctor(dirs, opts, stdout)
+		if err != nil {
+			fmt.Fprintf(stderr, "toolpaths: %v\n", err)
+		}
+		return code
+	}
+
+	entries, err := resolve(dirs, opts.kinds)
+	if err != nil {
+		fmt.Fprintf(stderr, "toolpaths: %v\n", err)
+		return exitError
+	}
+
+	switch opts.format {
+	case formatJSON:
+		err = writeJSON(stdout, entries)
+	case formatShell:
+		err = writeShell(stdout, opts.prefix, listSeparator(dirs.Platform()), entries)
+	default:
+		err = writeText(stdout, entries, len(opts.kinds) == 1)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "toolpaths: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func parseArgs(args []string, stderr io.Writer) (options, error) {
+	var opts options
+	var platform string
+
+	fs := flag.NewFlagSet("toolpaths", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.cfg.AppName, "app", "", "application name (required)")
+	fs.StringVar(&opts.cfg.AppAuthor, "author", "", "application author (Windows only)")
+	fs.StringVar(&opts.cfg.Version, "version", "", "application version subdirectory")
+	fs.StringVar(&opts.cfg.Profile, "profile", "", "profile subdirectory for the user directories")
+	fs.BoolVar(&opts.cfg.Roaming, "roaming", false, "use roaming AppData (Windows only)")
+	fs.BoolVar(&opts.cfg.XDGOnAllPlatforms, "xdg", false, "use XDG conventions on macOS and Windows")
+	fs.StringVar(&platform, "platform", "auto", "platform: auto, linux, macos, windows, freebsd, openbsd")
+	fs.StringVar(&opts.format, "format", formatText, "output format: text, json, shell")
+	fs.StringVar(&opts.prefix, "prefix", "", "variable name prefix for shell output (default: app name)")
+	fs.BoolVar(&opts.strict, "strict", false, "doctor: exit non-zero on warnings too")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: toolpaths --app NAME [flags] [kind...]\n")
+		fmt.Fprintf(fs.Output(), "       toolpaths --app NAME [flags] doctor\n\n")
+		fmt.Fprintf(fs.Output(), "Kinds: %s\n", strings.Join(toolpaths.Kinds(), ", "))
+		fmt.Fprintf(fs.Output(), "User kinds may be abbreviated: config, data, cache, state, log, runtime.\n\n")
+		fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	if strings.TrimSpace(opts.cfg.AppName) == "" {
+		return opts, errors.New("--app is required")
+	}
+
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return opts, err
+	}
+	opts.cfg.Platform = p
+
+	switch opts.format {
+	case formatText, formatJSON, formatShell:
+	default:
+		return opts, fmt.Errorf("unknown format %q (want text, json, or shell)", opts.format)
+	}
+
+	if opts.prefix == "" {
+		opts.prefix = opts.cfg.AppName
+	}
+
+	args = fs.Args()
+	if len(args) > 0 && args[0] == "doctor" {
+		if len(args) > 1 {
+			return opts, errors.New("doctor takes no arguments")
+		}
+		if opts.format == formatShell {
+			return opts, errors.New("doctor supports text and json formats")
+		}
+		opts.doctor = true
+		return opts, nil
+	}
+
+	for _, arg := range args {
+		kind, err := parseKind(arg)
+		if err != nil {
+			return opts, err
+		}
+		opts.kinds = append(opts.kinds, kind)
+	}
+	return opts, nil
+}
+
+func parsePlatform(name string) (toolpaths.Platform, error) {
+	for _, p := range []toolpaths.Platform{
+		toolpaths.PlatformAuto,
+		toolpaths.PlatformLinux,
+		toolpaths.PlatformMacOS,
+		toolpaths.PlatformWindows,
+		toolpaths.PlatformFreeBSD,
+		toolpaths.PlatformOpenBSD,
+	} {
+		if strings.EqualFold(name, p.String()) {
+			return p, nil
+		}
+	}
+	if strings.EqualFold(name, "darwin") {
+		return toolpaths.PlatformMacOS, nil
+	}
+	return toolpaths.PlatformAuto, fmt.Errorf("unknown platform %q", name)
+}
+
+// parseKind accepts a kind name or a user-kind abbreviation.
+func parseKind(arg string) (string, error) {
+	for _, kind := range toolpaths.Kinds() {
+		if arg == kind || "user-"+arg == kind {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown kind %q", arg)
+}
+
+// entry is one resolved directory.
+type entry struct {
+	Kind  string   `json:"kind"`
+	Path  string   `json:"path"`
+	Paths []string `json:"paths,omitempty"`
+}
+
+// resolve returns an entry for each kind, or for every available kind if
+// none are given. Explicitly requested kinds must resolve to a path.
+func resolve(dirs *toolpaths.PlatformDirs, kinds []string) ([]entry, error) {
+	var entries []entry
+	if len(kinds) == 0 {
+		for _, x := range dirs.ExplainAll() {
+			if x.Err == nil && x.Path != "" {
+				entries = append(entries, newEntry(x))
+			}
+		}
+		return entries, nil
+	}
+
+	for _, kind := range kinds {
+		x, err := dirs.Explain(kind)
+		if err != nil {
+			return nil, err
+		}
+		if x.Err != nil {
+			return nil, fmt.Errorf("%s: %w", kind, x.Err)
+		}
+		if x.Path == "" {
+			return nil, fmt.Errorf("%s: not available on %s", kind, dirs.Platform())
+		}
+		entries = append(entries, newEntry(x))
+	}
+	return entries, nil
+}
+
+func newEntry(x toolpaths.Explanation) entry {
+	e := entry{Kind: x.Kind, Path: x.Path}
+	if len(x.Paths) > 1 {
+		e.Paths = x.Paths
+	}
+	return e
+}
+
+// writeText prints "kind  path" lines, or just the path when a single
+// kind was requested so the output can be captured with $(...).
+func writeText(w io.Writer, entries []entry, bare bool) error {
+	width := 0
+	for _, e := range entries {
+		width = max(width, len(e.Kind))
+	}
+	for _, e := range entries {
+		var err error
+		if bare {
+			_, err = fmt.Fprintln(w, e.Path)
+		} else {
+			_, err = fmt.Fprintf(w, "%-*s  %s\n", width, e.Kind, e.Path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, entries []entry) error {
+	if entries == nil {
+		entries = []entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// writeShell prints POSIX shell assignments suitable for eval, e.g.
+// MYAPP_USER_CONFIG_DIR='/home/me/.config/myapp'. Kinds with a search
+// path also get a *_DIRS variable joined with sep.
+func writeShell(w io.Writer, prefix, sep string, entries []entry) error {
+	for _, e := range entries {
+		name := shellName(prefix + "_" + e.Kind)
+		if _, err := fmt.Fprintf(w, "%s_DIR=%s\n", name, shellQuote(e.Path)); err != nil {
+			return err
+		}
+		if len(e.Paths) > 1 {
+			joined := strings.Join(e.Paths, sep)
+			if _, err := fmt.Fprintf(w, "%s_DIRS=%s\n", name, shellQuote(joined)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listSeparator returns the path list separator of platform, so that
+// --platform windows joins search paths with ';' on any host.
+func listSeparator(platform toolpaths.Platform) string {
+	if platform == toolpaths.PlatformWindows {
+		return ";"
+	}
+	return ":"
+}
+
+// shellName upper-cases s and replaces characters not valid in a shell
+// variable name with underscores.
+func shellName(s string) string {
+	var b strings.Builder
+	for i, r := range strings.ToUpper(s) {
+		switch {
+		case r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// shellQuote wraps s in single quotes, escaping embedded single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
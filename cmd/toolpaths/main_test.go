@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runCmd(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func setXDG(t *testing.T) string {
+	t.Helper()
+	base := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(base, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(base, "data"))
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(base, "etc"))
+	return base
+}
+
+func TestRunSingleKind(t *testing.T) {
+	base := setXDG(t)
+
+	code, stdout, stderr := runCmd(t, "--app", "myapp", "--platform", "linux", "--version", "2", "config")
+	require.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, filepath.Join(base, "config", "myapp", "2")+"\n", stdout)
+
+	code, stdout, _ = runCmd(t, "--app", "myapp", "--platform", "linux", "user-data")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t, filepath.Join(base, "data", "myapp")+"\n", stdout)
+
+	code, stdout, _ = runCmd(t, "--app", "myapp", "--platform", "linux", "--profile", "work", "config")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t, filepath.Join(base, "config", "myapp", "profiles", "work")+"\n", stdout)
+}
+
+func TestRunText(t *testing.T) {
+	base := setXDG(t)
+
+	code, stdout, _ := runCmd(t, "--app", "myapp", "--platform", "linux", "config", "system-config")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t,
+		"user-config    "+filepath.Join(base, "config", "myapp")+"\n"+
+			"system-config  "+filepath.Join(base, "etc", "myapp")+"\n",
+		stdout)
+
+	code, stdout, _ = runCmd(t, "--app", "myapp", "--platform", "linux")
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "managed-recommended")
+}
+
+func TestRunJSON(t *testing.T) {
+	base := setXDG(t)
+
+	code, stdout, _ := runCmd(t, "--app", "myapp", "--platform", "linux", "--format", "json", "config")
+	require.Equal(t, exitOK, code)
+
+	var entries []entry
+	require.NoError(t, json.Unmarshal([]byte(stdout), &entries))
+	assert.Equal(t, []entry{{Kind: "user-config", Path: filepath.Join(base, "config", "myapp")}}, entries)
+}
+
+func TestRunShell(t *testing.T) {
+	setXDG(t)
+
+	code, stdout, _ := runCmd(t,
+		"--app", "my-app", "--platform", "macos", "--format", "shell", "config", "system-config")
+	require.Equal(t, exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "MY_APP_USER_CONFIG_DIR='"), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "MY_APP_USER_CONFIG_DIRS='"), lines[1])
+	assert.Contains(t, lines[1], ":", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "MY_APP_SYSTEM_CONFIG_DIR='"), lines[2])
+
+	code, stdout, _ = runCmd(t,
+		"--app", "myapp", "--platform", "linux", "--format", "shell", "--prefix", "x", "runtime")
+	require.Equal(t, exitOK, code)
+	assert.True(t, strings.HasPrefix(stdout, "X_USER_RUNTIME_DIR='"), stdout)
+
+	code, stdout, _ = runCmd(t,
+		"--app", "myapp", "--platform", "windows", "--format", "shell", "config")
+	require.Equal(t, exitOK, code)
+	lines = strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], ";", "search paths use the selected platform's separator")
+}
+
+func TestRunWindowsAuthorRoaming(t *testing.T) {
+	code, stdout, _ := runCmd(t,
+		"--app", "myapp", "--platform", "windows", "--author", "Acme", "--roaming", "config")
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, filepath.Join("AppData", "Roaming", "Acme", "myapp"))
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"missing app", []string{"config"}, exitUsage, "--app is required"},
+		{"bad platform", []string{"--app", "x", "--platform", "beos"}, exitUsage, "unknown platform"},
+		{"bad format", []string{"--app", "x", "--format", "xml"}, exitUsage, "unknown format"},
+		{"bad kind", []string{"--app", "x", "music"}, exitUsage, "unknown kind"},
+		{"unavailable", []string{"--app", "x", "--platform", "macos", "system-runtime"}, exitError, "not available"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCmd(t, tt.args...)
+			assert.Equal(t, tt.code, code)
+			assert.Empty(t, stdout)
+			assert.Contains(t, stderr, tt.want)
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'plain'`, shellQuote("plain"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
+
+func TestShellName(t *testing.T) {
+	assert.Equal(t, "MY_APP_USER_CONFIG", shellName("my-app_user-config"))
+	assert.Equal(t, "_9LIVES", shellName("9lives"))
+}
@@ -0,0 +1,281 @@
+package toolpaths
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// DirInfo describes one resolved directory and its state on disk.
+type DirInfo struct {
+	// Kind names the directory type, e.g. "user-config" or "system-data".
+	Kind string
+
+	// Path is the resolved directory. Empty if the platform has no
+	// equivalent (e.g., system runtime on macOS) or resolution failed.
+	Path string
+
+	// Primary is true for the write location of Kind. Search-path entries
+	// after the first (XDG fallbacks, additional XDG_CONFIG_DIRS) are false.
+	Primary bool
+
+	// Err is the resolution error, if any (only the user runtime directory
+	// can fail to resolve).
+	Err error
+
+	// Exists reports whether Path exists. Mode and Owner are only set when
+	// it does.
+	Exists bool
+
+	// Mode is the file mode of Path, including the type bits.
+	Mode fs.FileMode
+
+	// Owner identifies the owning user as "name (uid)" on Unix. Empty on
+	// platforms without Unix ownership.
+	Owner string
+
+	// UID is the numeric owner on Unix, or -1 if unknown.
+	UID int
+}
+
+// String returns a one-line summary of the directory.
+func (i DirInfo) String() string {
+	switch {
+	case i.Err != nil:
+		return fmt.Sprintf("%-19s <error: %v>", i.Kind, i.Err)
+	case i.Path == "":
+		return fmt.Sprintf("%-19s <not available>", i.Kind)
+	case !i.Exists:
+		return fmt.Sprintf("%-19s %s (missing)", i.Kind, i.Path)
+	}
+	s := fmt.Sprintf("%-19s %s (%s", i.Kind, i.Path, i.Mode)
+	if i.Owner != "" {
+		s += ", owner " + i.Owner
+	}
+	return s + ")"
+}
+
+// EnvVar records an environment variable that can influence resolution.
+type EnvVar struct {
+	Name  string
+	Value string
+	Set   bool // false if the variable is not present in the environment
+}
+
+// String returns NAME=value, or NAME=<unset>.
+func (e EnvVar) String() string {
+	if !e.Set {
+		return e.Name + "=<unset>"
+	}
+	return fmt.Sprintf("%s=%q", e.Name, e.Value)
+}
+
+// Diagnostics is a snapshot of how a PlatformDirs resolves its directories.
+type Diagnostics struct {
+	AppName   string
+	AppAuthor string
+	Version   string
+	Platform  Platform
+	Sandbox   Sandbox
+
+	// Invoker is the sudo or doas user directories resolve for, if any.
+	Invoker Invoker
+
+	// Portable is the portable root, or empty if portable mode is off.
+	Portable string
+
+	// Profile is the active profile, or empty if none is selected.
+	Profile string
+
+	// Dirs lists every directory type, user directories first, with
+	// search-path entries in priority order.
+	Dirs []DirInfo
+
+	// Env lists the environment variables consulted during resolution.
+	Env []EnvVar
+}
+
+// String returns a multi-line report suitable for pasting into bug reports.
+func (diag Diagnostics) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "toolpaths diagnostics for %q\n", diag.AppName)
+	if diag.AppAuthor != "" {
+		fmt.Fprintf(&b, "  AppAuthor: %q\n", diag.AppAuthor)
+	}
+	if diag.Version != "" {
+		fmt.Fprintf(&b, "  Version:   %q\n", diag.Version)
+	}
+	fmt.Fprintf(&b, "  Platform:  %s\n", diag.Platform)
+	if diag.Sandbox.Kind != SandboxNone {
+		fmt.Fprintf(&b, "  Sandbox:   %s %q\n", diag.Sandbox.Kind, diag.Sandbox.ID)
+	}
+	if diag.Invoker.Name != "" {
+		fmt.Fprintf(&b, "  Invoker:   %s (uid %d, via %s)\n", diag.Invoker.Name, diag.Invoker.UID, diag.Invoker.Via)
+	}
+	if diag.Portable != "" {
+		fmt.Fprintf(&b, "  Portable:  %s\n", diag.Portable)
+	}
+	if diag.Profile != "" {
+		fmt.Fprintf(&b, "  Profile:   %q\n", diag.Profile)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Directories:\n")
+	for _, info := range diag.Dirs {
+		b.WriteString("  ")
+		b.WriteString(info.String())
+		if !info.Primary {
+			b.WriteString(" [fallback]")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	b.WriteString(envString(diag.Env))
+	return b.String()
+}
+
+// Diagnose resolves every directory type and reports its path, whether it
+// exists, and its permissions and owner, along with the environment that
+// influenced resolution.
+func (d *PlatformDirs) Diagnose() Diagnostics {
+	diag := Diagnostics{
+		AppName:   d.cfg.AppName,
+		AppAuthor: d.cfg.AppAuthor,
+		Version:   d.cfg.Version,
+		Platform:  d.platform,
+		Sandbox:   d.sandbox,
+		Invoker:   d.invoker,
+		Portable:  d.portable,
+		Profile:   d.profile,
+		Env:       d.DiagnoseEnv(),
+	}
+
+	add := func(dt dirType, paths ...string) {
+		for i, p := range paths {
+			diag.Dirs = append(diag.Dirs, d.statDir(DirInfo{Kind: dt.String(), Path: p, Primary: i == 0}))
+		}
+	}
+
+	add(userConfig, d.UserConfigDirs()...)
+	add(userData, d.UserDataDirs()...)
+	add(userCache, d.UserCacheDirs()...)
+	add(userState, d.UserStateDirs()...)
+	add(userLog, d.UserLogDirs()...)
+	runtimeDir, err := d.UserRuntimeDir()
+	diag.Dirs = append(diag.Dirs, d.statDir(DirInfo{Kind: userRuntime.String(), Path: runtimeDir, Primary: true, Err: err}))
+
+	add(systemConfig, d.SystemConfigDirs()...)
+	add(systemData, d.SystemDataDirs()...)
+	// Single-directory kinds list every systemd directory when a service
+	// sets several, and an empty entry when unavailable on the platform.
+	addSingle := func(dt dirType, dir string) {
+		if dirs := d.withSystemdDirs(dt, dir); len(dirs) > 0 {
+			add(dt, dirs...)
+		} else {
+			add(dt, dir)
+		}
+	}
+	addSingle(systemCache, d.SystemCacheDir())
+	addSingle(systemState, d.SystemStateDir())
+	addSingle(systemLog, d.SystemLogDir())
+	addSingle(systemRuntime, d.SystemRuntimeDir())
+	add(managedRequired, d.ManagedRequiredConfigDir())
+	add(managedRecommended, d.ManagedRecommendedConfigDir())
+
+	return diag
+}
+
+// DiagnoseEnv returns the environment variables that can influence
+// resolution: the XDG base directory variables, HOME, the Windows folder
+// variables, TMPDIR, the systemd service directory variables when
+// Config.Systemd is set, the sudo and doas variables when Config.SudoAware
+// is set, the sandbox variables inside Flatpak or Snap, the
+// HomeEnv, Portable.EnvVar, and ProfileEnv, and any names configured in
+// EnvOverrides.
+func (d *PlatformDirs) DiagnoseEnv() []EnvVar {
+	names := []string{
+		"XDG_CONFIG_HOME",
+		"XDG_DATA_HOME",
+		"XDG_CACHE_HOME",
+		"XDG_STATE_HOME",
+		"XDG_RUNTIME_DIR",
+		"XDG_CONFIG_DIRS",
+		"XDG_DATA_DIRS",
+		"HOME",
+		"APPDATA",
+		"LOCALAPPDATA",
+		"ProgramData",
+		"TMPDIR",
+	}
+	if d.cfg.Systemd != SystemdOff {
+		names = append(names, systemdEnvNames...)
+	}
+	switch d.sandbox.Kind {
+	case SandboxFlatpak:
+		names = append(names, flatpakEnvNames...)
+	case SandboxSnap:
+		names = append(names, snapEnvNames...)
+	case SandboxNone:
+	}
+	if d.cfg.SudoAware {
+		names = append(names, sudoEnvNames...)
+	}
+	if d.cfg.HomeEnv != "" {
+		names = append(names, d.cfg.HomeEnv)
+	}
+	if d.cfg.Portable != nil && d.cfg.Portable.EnvVar != "" {
+		names = append(names, d.cfg.Portable.EnvVar)
+	}
+	if d.cfg.ProfileEnv != "" {
+		names = append(names, d.cfg.ProfileEnv)
+	}
+	if d.cfg.EnvOverrides != nil {
+		for dt := userConfig; dt <= managedRecommended; dt++ {
+			if name := d.cfg.EnvOverrides.get(dt); name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	vars := make([]EnvVar, 0, len(names))
+	for _, name := range names {
+		value, set := d.lookupEnv(name)
+		vars = append(vars, EnvVar{Name: name, Value: value, Set: set})
+	}
+	return vars
+}
+
+// DiagnoseEnvString returns DiagnoseEnv formatted one variable per line.
+func (d *PlatformDirs) DiagnoseEnvString() string {
+	return envString(d.DiagnoseEnv())
+}
+
+func envString(vars []EnvVar) string {
+	var b strings.Builder
+	b.WriteString("Environment:\n")
+	for _, v := range vars {
+		b.WriteString("  ")
+		b.WriteString(v.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// statDir fills in the on-disk fields of info.
+func (d *PlatformDirs) statDir(info DirInfo) DirInfo {
+	info.UID = -1
+	if info.Path == "" || info.Err != nil {
+		return info
+	}
+	fi, err := d.fsys.Stat(info.Path)
+	if err != nil {
+		return info
+	}
+	info.Exists = true
+	info.Mode = fi.Mode()
+	info.Owner, info.UID = fileOwner(fi)
+	return info
+}
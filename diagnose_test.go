@@ -0,0 +1,143 @@
+package toolpaths_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func findDirInfo(t *testing.T, diag toolpaths.Diagnostics, kind string) []toolpaths.DirInfo {
+	t.Helper()
+	var infos []toolpaths.DirInfo
+	for _, info := range diag.Dirs {
+		if info.Kind == kind {
+			infos = append(infos, info)
+		}
+	}
+	require.NotEmpty(t, infos, "no %s entry", kind)
+	return infos
+}
+
+func TestDiagnose(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(home, "etc1")+string(filepath.ListSeparator)+filepath.Join(home, "etc2"))
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(home, "run"))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "testapp",
+		Version:  "1.0",
+		Platform: toolpaths.PlatformLinux,
+	})
+	require.NoError(t, err)
+
+	configDir := dirs.UserConfigDir()
+	require.NoError(t, os.MkdirAll(configDir, 0o700))
+
+	diag := dirs.Diagnose()
+	assert.Equal(t, "testapp", diag.AppName)
+	assert.Equal(t, "1.0", diag.Version)
+	assert.Equal(t, toolpaths.PlatformLinux, diag.Platform)
+
+	config := findDirInfo(t, diag, "user-config")
+	require.Len(t, config, 1)
+	assert.Equal(t, configDir, config[0].Path)
+	assert.True(t, config[0].Primary)
+	assert.True(t, config[0].Exists)
+	assert.True(t, config[0].Mode.IsDir())
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0o700), config[0].Mode.Perm())
+		assert.NotEmpty(t, config[0].Owner)
+		assert.Equal(t, os.Getuid(), config[0].UID)
+	}
+
+	data := findDirInfo(t, diag, "user-data")
+	assert.False(t, data[0].Exists)
+	assert.Zero(t, data[0].Mode)
+	assert.Empty(t, data[0].Owner)
+	assert.Equal(t, -1, data[0].UID)
+
+	system := findDirInfo(t, diag, "system-config")
+	require.Len(t, system, 2)
+	assert.True(t, system[0].Primary)
+	assert.False(t, system[1].Primary)
+
+	rt := findDirInfo(t, diag, "user-runtime")
+	assert.Equal(t, filepath.Join(home, "run", "testapp", "1.0"), rt[0].Path)
+
+	for _, kind := range []string{
+		"user-cache", "user-state", "user-log",
+		"system-data", "system-cache", "system-state", "system-log", "system-runtime",
+		"managed-required", "managed-recommended",
+	} {
+		findDirInfo(t, diag, kind)
+	}
+
+	s := diag.String()
+	assert.Contains(t, s, `toolpaths diagnostics for "testapp"`)
+	assert.Contains(t, s, configDir)
+	assert.Contains(t, s, "(missing)")
+	assert.Contains(t, s, "[fallback]")
+	assert.Contains(t, s, "Environment:")
+}
+
+func TestDiagnoseNotAvailable(t *testing.T) {
+	setTestHomeXDG(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "testapp",
+		Platform: toolpaths.PlatformMacOS,
+	})
+	require.NoError(t, err)
+
+	info := findDirInfo(t, dirs.Diagnose(), "system-runtime")
+	assert.Empty(t, info[0].Path)
+	assert.False(t, info[0].Exists)
+	assert.Contains(t, info[0].String(), "<not available>")
+}
+
+func TestDiagnoseEnv(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("TESTAPP_CONFIG", filepath.Join(home, "override"))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "testapp",
+		Platform: toolpaths.PlatformLinux,
+		EnvOverrides: &toolpaths.EnvOverrides{
+			UserConfig:         "TESTAPP_CONFIG",
+			UserData:           "TESTAPP_DATA",
+			ManagedRecommended: "TESTAPP_CONFIG", // duplicates are listed once
+		},
+	})
+	require.NoError(t, err)
+
+	vars := map[string]toolpaths.EnvVar{}
+	var names []string
+	for _, v := range dirs.DiagnoseEnv() {
+		vars[v.Name] = v
+		names = append(names, v.Name)
+	}
+
+	for _, name := range []string{
+		"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME",
+		"XDG_RUNTIME_DIR", "XDG_CONFIG_DIRS", "XDG_DATA_DIRS",
+		"HOME", "APPDATA", "TMPDIR", "TESTAPP_CONFIG", "TESTAPP_DATA",
+	} {
+		assert.Contains(t, names, name)
+	}
+	assert.Len(t, names, len(vars), "names should be unique")
+
+	assert.Equal(t, toolpaths.EnvVar{Name: "HOME", Value: home, Set: true}, vars["HOME"])
+	assert.True(t, vars["TESTAPP_CONFIG"].Set)
+	assert.False(t, vars["TESTAPP_DATA"].Set)
+
+	s := dirs.DiagnoseEnvString()
+	assert.True(t, strings.HasPrefix(s, "Environment:\n"))
+	assert.Contains(t, s, "TESTAPP_DATA=<unset>")
+	assert.Contains(t, s, `HOME="`+strings.ReplaceAll(home, `\`, `\\`)+`"`)
+}
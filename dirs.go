@@ -1,5 +1,7 @@
 package toolpaths
 
+import "context"
+
 // Platform represents the detected or overridden operating system.
 type Platform int
 
@@ -45,6 +47,42 @@ type Config struct {
 	// Version is optional. If set, appended as a subdirectory.
 	Version string
 
+	// Unversioned lists directory kinds, as in DirInfo.Kind (e.g.,
+	// "user-config"), that omit Version, so config can stay shared across
+	// releases while the cache is versioned. NewWithConfig returns
+	// ErrUnknownKind for an unrecognized kind.
+	Unversioned []string
+
+	// VersionFallback makes User*Dirs, Find*File, Existing*Files, and
+	// All*Paths fall back to the directory of the newest older version
+	// that exists (e.g., myapp/2.4 when Version is "3"), searched right
+	// after the current one. Versions are compared as semantic versions.
+	// The older directory is only read: Migrate never moves it, so a
+	// downgrade still finds its data. Use PlatformDirs.CopyForward to seed
+	// the current version from it. See also PlatformDirs.Versions.
+	VersionFallback bool
+
+	// Profile namespaces the user directories under profiles/{Profile}
+	// below the app path (e.g., ~/.config/myapp/profiles/work), so each
+	// profile gets isolated config, data, cache, state, log, and runtime
+	// directories. It applies to EnvOverrides, HomeEnv, and portable
+	// directories too; systemd directories and the system and managed
+	// directories are shared. If empty, ProfileEnv selects the profile.
+	// NewWithConfig returns ErrInvalidProfile for a name that is not a
+	// single path element or starts with a dot.
+	Profile string
+
+	// ProfileEnv names a variable selecting the profile when Profile is
+	// empty, such as "MYAPP_PROFILE". EnvPrefix sets it to {prefix}_PROFILE
+	// if it is empty.
+	ProfileEnv string
+
+	// Unprofiled lists user directory kinds, as in DirInfo.Kind (e.g.,
+	// "user-cache"), that every profile shares. NewWithConfig returns
+	// ErrUnknownKind for any other kind, including system and managed
+	// kinds, which are never profiled.
+	Unprofiled []string
+
 	// Roaming controls Windows behavior only.
 	// true = FOLDERID_RoamingAppData
 	// false = FOLDERID_LocalAppData
@@ -73,15 +111,114 @@ type Config struct {
 	// This option has no effect on XDG platforms (Linux, FreeBSD, OpenBSD) where XDG is native.
 	IncludeXDGFallbacks *bool
 
+	// LegacyPaths declares pre-XDG locations such as ~/.myapp and
+	// ~/.myapprc, searched after every other location and migrated by
+	// Migrate. If nil (default), none are used. See LegacyPaths.
+	LegacyPaths *LegacyPaths
+
 	// EnvOverrides allows specifying app-specific environment variables
 	// that take precedence over all other resolution strategies.
 	// If the env var is set and non-empty, its value is used directly
 	// (with AppName/Version appended according to AppendAppName setting).
 	EnvOverrides *EnvOverrides
 
+	// EnvPrefix derives environment variable names from a prefix. "MYAPP"
+	// fills every empty EnvOverrides field with the names from
+	// EnvOverridesForPrefix (MYAPP_CONFIG_HOME, MYAPP_CACHE_HOME, ...) and
+	// sets HomeEnv to MYAPP_HOME and ProfileEnv to MYAPP_PROFILE if they
+	// are empty.
+	EnvPrefix string
+
+	// HomeEnv names a variable holding a single root for every user
+	// directory, like CARGO_HOME or GNUPGHOME. When it is set and non-empty,
+	// the user directories resolve to {root}/config, {root}/data,
+	// {root}/cache, {root}/state, {root}/log, and {root}/runtime, used
+	// as-is. Per-kind EnvOverrides still take precedence.
+	HomeEnv string
+
+	// HomeEnvIncludesSystem also relocates the system directories under
+	// {root}/system (e.g., {root}/system/config). Managed policy
+	// directories are never relocated.
+	HomeEnvIncludesSystem bool
+
+	// StrictXDG applies the XDG Base Directory specification strictly:
+	// relative paths in XDG_*_HOME, XDG_RUNTIME_DIR, XDG_CONFIG_DIRS, and
+	// XDG_DATA_DIRS are ignored, repeated search path entries are removed,
+	// and XDG_RUNTIME_DIR must exist, be owned by the user, and have mode
+	// 0700, or UserRuntimeDir fails. Each violation is passed to
+	// OnXDGViolation.
+	StrictXDG bool
+
+	// OnXDGViolation receives every violation StrictXDG finds. Resolution
+	// is not cached, so a value may be reported each time it is read. If
+	// nil, violations are still enforced but not reported.
+	OnXDGViolation func(*XDGViolation)
+
 	// Platform overrides OS detection. Useful for testing.
 	// Leave as PlatformAuto (zero value) for automatic detection.
 	Platform Platform
+
+	// Systemd opts in to the directories systemd exports for services
+	// ($CONFIGURATION_DIRECTORY, $STATE_DIRECTORY, $CACHE_DIRECTORY,
+	// $LOGS_DIRECTORY, $RUNTIME_DIRECTORY). SystemdSystem applies them to
+	// the System* directories, SystemdUser to the User* directories. When a
+	// variable is set, its first entry becomes the directory and every entry
+	// is searched by the Find*, Existing*, and *Dirs methods. EnvOverrides
+	// still take precedence. Default: SystemdOff.
+	Systemd SystemdMode
+
+	// Portable enables portable installs that keep every user directory
+	// next to the executable. If nil (default), portable mode is off. See
+	// Portable and PlatformDirs.PortableRoot.
+	Portable *Portable
+
+	// IgnoreSandbox disables Flatpak and Snap detection, so directories
+	// resolve as they would outside a sandbox. See PlatformDirs.Sandbox.
+	IgnoreSandbox bool
+
+	// LookupEnv replaces os.LookupEnv for every environment variable read
+	// during resolution, including HOME outside Windows; without HOME the
+	// home directory comes from the user database, not the process
+	// environment. If nil, the process environment is used. Together with HomeDir, TempDir, and UID
+	// it lets PlatformDirs with different environments coexist, which
+	// SetHomeDirFunc cannot.
+	LookupEnv func(key string) (string, bool)
+
+	// HomeDir replaces the user's home directory. On Windows, the Known
+	// Folders are then derived from it (e.g., {HomeDir}\AppData\Local)
+	// instead of queried from the system. If empty, $HOME (through
+	// LookupEnv) or os.UserHomeDir is used. See PlatformDirs.HomeDir.
+	HomeDir string
+
+	// HomeFallback is used as the home directory when none can be
+	// determined (no HOME, no user database entry), such as a service
+	// account's state directory (e.g., "/var/lib/myapp").
+	HomeFallback string
+
+	// RequireHomeDir makes NewWithConfig fail with ErrNoHomeDir when no
+	// absolute home directory can be determined, rather than building
+	// user directories relative to the working directory.
+	RequireHomeDir bool
+
+	// TempDir replaces os.TempDir for the runtime directory fallback.
+	TempDir string
+
+	// UID replaces os.Getuid for the runtime directory fallback on Linux
+	// and BSD ({TempDir}/{AppName}-{uid}).
+	UID func() int
+
+	// SudoAware resolves the user directories for the user who ran the
+	// process through sudo or doas (SUDO_USER, DOAS_USER) when it runs as
+	// root, instead of for root: the invoker's home directory and UID
+	// replace HOME and UID. An explicit HomeDir is kept. See
+	// PlatformDirs.Invoker and ChownToInvoker.
+	// Ignored on Windows.
+	SudoAware bool
+
+	// FS is the filesystem used by Find*, Existing*, FindUp*, and Ensure*
+	// methods, and by cascades built on these dirs. If nil, the real
+	// filesystem is used. Set it to a MapFS to test against an in-memory tree.
+	FS FS
 }
 
 // EnvOverrides specifies app-specific environment variables for each
@@ -104,6 +241,63 @@ type EnvOverrides struct {
 	SystemState   string // e.g., "MYAPP_SYSTEM_STATE"
 	SystemLog     string // e.g., "MYAPP_SYSTEM_LOG"
 	SystemRuntime string // e.g., "MYAPP_SYSTEM_RUNTIME"
+
+	// ManagedRecommended names the variable for the managed-recommended
+	// directory. The managed-required directory has no variable: enforced
+	// policy must not be movable by the user it constrains.
+	ManagedRecommended string // e.g., "MYAPP_MANAGED_RECOMMENDED"
+}
+
+// EnvOverridesForPrefix returns EnvOverrides whose variable names derive
+// from prefix: {prefix}_CONFIG_HOME, {prefix}_DATA_HOME, {prefix}_CACHE_HOME,
+// {prefix}_STATE_HOME, {prefix}_LOG_HOME, {prefix}_RUNTIME_DIR, then
+// {prefix}_SYSTEM_CONFIG, ..., {prefix}_SYSTEM_RUNTIME, and
+// {prefix}_MANAGED_RECOMMENDED.
+func EnvOverridesForPrefix(prefix string) *EnvOverrides {
+	return &EnvOverrides{
+		UserConfig:         prefix + "_CONFIG_HOME",
+		UserData:           prefix + "_DATA_HOME",
+		UserCache:          prefix + "_CACHE_HOME",
+		UserState:          prefix + "_STATE_HOME",
+		UserLog:            prefix + "_LOG_HOME",
+		UserRuntime:        prefix + "_RUNTIME_DIR",
+		SystemConfig:       prefix + "_SYSTEM_CONFIG",
+		SystemData:         prefix + "_SYSTEM_DATA",
+		SystemCache:        prefix + "_SYSTEM_CACHE",
+		SystemState:        prefix + "_SYSTEM_STATE",
+		SystemLog:          prefix + "_SYSTEM_LOG",
+		SystemRuntime:      prefix + "_SYSTEM_RUNTIME",
+		ManagedRecommended: prefix + "_MANAGED_RECOMMENDED",
+	}
+}
+
+// withDefaults returns a copy of e with every empty variable name taken
+// from defaults. A nil e yields a copy of defaults.
+func (e *EnvOverrides) withDefaults(defaults *EnvOverrides) *EnvOverrides {
+	merged := *defaults
+	if e == nil {
+		return &merged
+	}
+	merged.AppendAppName = e.AppendAppName
+	fill := func(field *string, name string) {
+		if name != "" {
+			*field = name
+		}
+	}
+	fill(&merged.UserConfig, e.UserConfig)
+	fill(&merged.UserData, e.UserData)
+	fill(&merged.UserCache, e.UserCache)
+	fill(&merged.UserState, e.UserState)
+	fill(&merged.UserLog, e.UserLog)
+	fill(&merged.UserRuntime, e.UserRuntime)
+	fill(&merged.SystemConfig, e.SystemConfig)
+	fill(&merged.SystemData, e.SystemData)
+	fill(&merged.SystemCache, e.SystemCache)
+	fill(&merged.SystemState, e.SystemState)
+	fill(&merged.SystemLog, e.SystemLog)
+	fill(&merged.SystemRuntime, e.SystemRuntime)
+	fill(&merged.ManagedRecommended, e.ManagedRecommended)
+	return &merged
 }
 
 // get returns the env var name for the given directory type.
@@ -134,6 +328,8 @@ func (e *EnvOverrides) get(dt dirType) string {
 		return e.SystemLog
 	case systemRuntime:
 		return e.SystemRuntime
+	case managedRecommended:
+		return e.ManagedRecommended
 	default:
 		return ""
 	}
@@ -277,5 +473,76 @@ type Dirs interface {
 	) []Match
 }
 
-// Compile-time check that PlatformDirs implements Dirs.
-var _ Dirs = (*PlatformDirs)(nil)
+// The interfaces below extend Dirs with lookups added after it. They are
+// separate so that adding them does not break existing implementations of
+// Dirs; PlatformDirs and FakeDirs implement all of them. Check for one with
+// a type assertion:
+//
+//	if vf, ok := dirs.(toolpaths.VariantFinder); ok {
+//		m, found := vf.FindConfigFileVariant("config", nil)
+//	}
+
+// VariantFinder finds files under every filename a FileResolver produces
+// for a basename (VariantBoth if the resolver is nil) and reports which
+// variant matched and whether other variants also exist.
+type VariantFinder interface {
+	FindConfigFileVariant(basename string, resolver FileResolver) (VariantMatch, bool)
+	ExistingConfigFileVariants(basename string, resolver FileResolver) []VariantMatch
+	FindDataFileVariant(basename string, resolver FileResolver) (VariantMatch, bool)
+	ExistingDataFileVariants(basename string, resolver FileResolver) []VariantMatch
+	FindCacheFileVariant(basename string, resolver FileResolver) (VariantMatch, bool)
+	ExistingCacheFileVariants(basename string, resolver FileResolver) []VariantMatch
+	FindStateFileVariant(basename string, resolver FileResolver) (VariantMatch, bool)
+	ExistingStateFileVariants(basename string, resolver FileResolver) []VariantMatch
+	FindLogFileVariant(basename string, resolver FileResolver) (VariantMatch, bool)
+	ExistingLogFileVariants(basename string, resolver FileResolver) []VariantMatch
+	FindRuntimeFileVariant(basename string, resolver FileResolver) (VariantMatch, bool)
+	ExistingRuntimeFileVariants(basename string, resolver FileResolver) []VariantMatch
+}
+
+// ManagedDirs resolves the managed policy directories, which hold
+// administrator-deployed config. Required settings override user config;
+// recommended settings are defaults that user and system config can
+// override. A Cascade whose Dirs does not implement it has no managed
+// scopes.
+type ManagedDirs interface {
+	ManagedRequiredConfigDir() string
+	ManagedRequiredConfigPath(elem ...string) string
+	ManagedRecommendedConfigDir() string
+	ManagedRecommendedConfigPath(elem ...string) string
+
+	// Managed find utilities search managed-required, then user and system
+	// config, then managed-recommended.
+	FindManagedConfigFile(filename string) (string, bool)
+	AllManagedConfigPaths(filename string) []string
+	ExistingManagedConfigFiles(filename string) []string
+}
+
+// MediaDirs resolves the user media directories. They are shared by all
+// applications, so the app name is not appended.
+type MediaDirs interface {
+	UserDesktopDir() string
+	UserDocumentsDir() string
+	UserDownloadDir() string
+	UserMusicDir() string
+	UserPicturesDir() string
+	UserVideosDir() string
+	UserTemplatesDir() string
+	UserPublicDir() string
+}
+
+// ContextFinder walks up the directory tree like the FindUp methods, with
+// cancellation, depth, home, filesystem, and ceiling limits.
+type ContextFinder interface {
+	FindUpContext(ctx context.Context, start string, markers []string, opts FindUpOptions) (Match, bool, error)
+	FindAllUpContext(ctx context.Context, start string, markers []string, opts FindUpOptions) ([]Match, error)
+}
+
+// Compile-time checks that PlatformDirs implements Dirs and its extensions.
+var (
+	_ Dirs          = (*PlatformDirs)(nil)
+	_ VariantFinder = (*PlatformDirs)(nil)
+	_ ManagedDirs   = (*PlatformDirs)(nil)
+	_ MediaDirs     = (*PlatformDirs)(nil)
+	_ ContextFinder = (*PlatformDirs)(nil)
+)
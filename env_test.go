@@ -0,0 +1,107 @@
+package toolpaths_test
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// mapEnv returns a LookupEnv function backed by env.
+func mapEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestInjectedEnvironment(t *testing.T) {
+	base := testBase()
+
+	for i, platform := range []toolpaths.Platform{toolpaths.PlatformLinux, toolpaths.PlatformWindows} {
+		for tenant := range 3 {
+			t.Run(fmt.Sprintf("%s/tenant-%d", platform, tenant), func(t *testing.T) {
+				t.Parallel()
+				home := p(base, "home", fmt.Sprintf("tenant-%d-%d", i, tenant))
+				dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+					AppName:       "myapp",
+					Platform:      platform,
+					HomeDir:       home,
+					LookupEnv:     mapEnv(map[string]string{"XDG_CACHE_HOME": p(home, "xdg-cache")}),
+					TempDir:       p(base, "tmp"),
+					UID:           func() int { return 1000 + tenant },
+					FS:            toolpaths.NewMapFS(),
+					IgnoreSandbox: true,
+				})
+				require.NoError(t, err)
+
+				assert.Equal(t, p(home, "xdg-cache", "myapp"), dirs.UserCacheDir())
+				if platform == toolpaths.PlatformWindows {
+					assert.Equal(t, p(home, "AppData", "Local", "myapp"), dirs.UserConfigDir())
+					return
+				}
+				assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir())
+				rt, err := dirs.UserRuntimeDir()
+				require.NoError(t, err)
+				assert.Equal(t, p(base, "tmp", fmt.Sprintf("myapp-%d", 1000+tenant)), rt)
+				assert.Equal(t, p(home, "Desktop"), dirs.UserDesktopDir())
+			})
+		}
+	}
+}
+
+func TestLookupEnvReplacesProcessEnvironment(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("XDG_CONFIG_HOME", p(home, "process-config"))
+	t.Setenv("MYAPP_DATA", p(home, "process-data"))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:      "myapp",
+		Platform:     toolpaths.PlatformLinux,
+		LookupEnv:    mapEnv(map[string]string{"MYAPP_DATA": p(home, "injected-data")}),
+		EnvOverrides: &toolpaths.EnvOverrides{UserData: "MYAPP_DATA"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir(), "process XDG_CONFIG_HOME is not seen")
+	assert.Equal(t, p(home, "injected-data"), dirs.UserDataDir())
+
+	for _, v := range dirs.DiagnoseEnv() {
+		if v.Name == "XDG_CONFIG_HOME" {
+			assert.False(t, v.Set)
+		}
+	}
+}
+
+func TestLookupEnvIgnoresProcessHome(t *testing.T) {
+	processHome := p(t.TempDir(), "process-home")
+	t.Setenv("HOME", processHome)
+	t.Setenv("USERPROFILE", processHome)
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", LookupEnv: mapEnv(nil)})
+	require.NoError(t, err)
+	home, err := dirs.HomeDir()
+	if u, uerr := user.Current(); uerr == nil && filepath.IsAbs(u.HomeDir) {
+		require.NoError(t, err)
+		assert.Equal(t, u.HomeDir, home, "the user database follows an injected environment")
+	}
+	assert.NotEqual(t, processHome, home, "the process HOME is not seen")
+
+	injected := p(testBase(), "tenant")
+	dirs, err = toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		LookupEnv: mapEnv(map[string]string{"HOME": injected}),
+	})
+	require.NoError(t, err)
+	home, err = dirs.HomeDir()
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, injected, home)
+	}
+}
@@ -0,0 +1,103 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestEnvOverridesForPrefix(t *testing.T) {
+	o := toolpaths.EnvOverridesForPrefix("MYAPP")
+	assert.Equal(t, "MYAPP_CONFIG_HOME", o.UserConfig)
+	assert.Equal(t, "MYAPP_CACHE_HOME", o.UserCache)
+	assert.Equal(t, "MYAPP_RUNTIME_DIR", o.UserRuntime)
+	assert.Equal(t, "MYAPP_SYSTEM_STATE", o.SystemState)
+	assert.Equal(t, "MYAPP_MANAGED_RECOMMENDED", o.ManagedRecommended)
+	assert.False(t, o.AppendAppName)
+}
+
+func TestEnvPrefix(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("MYAPP_CACHE_HOME", p(home, "cache-env"))
+	t.Setenv("MYAPP_SYSTEM_LOG", p(home, "syslog-env"))
+	t.Setenv("MYAPP_HOME", "")
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		EnvPrefix: "MYAPP_",
+		EnvOverrides: &toolpaths.EnvOverrides{
+			AppendAppName: true,
+			UserConfig:    "CUSTOM_CONFIG",
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, "cache-env", "myapp"), dirs.UserCacheDir(), "derived name, AppendAppName kept")
+	assert.Equal(t, p(home, "syslog-env", "myapp"), dirs.SystemLogDir())
+
+	t.Setenv("CUSTOM_CONFIG", p(home, "custom"))
+	t.Setenv("MYAPP_CONFIG_HOME", p(home, "derived"))
+	assert.Equal(t, p(home, "custom", "myapp"), dirs.UserConfigDir(), "explicit fields win")
+
+	var names []string
+	for _, v := range dirs.DiagnoseEnv() {
+		names = append(names, v.Name)
+	}
+	assert.Contains(t, names, "MYAPP_HOME")
+	assert.Contains(t, names, "MYAPP_STATE_HOME")
+}
+
+func TestHomeEnv(t *testing.T) {
+	home := setTestHomeXDG(t)
+	root := p(home, "myapp-home")
+	t.Setenv("MYAPP_HOME", root)
+	t.Setenv("MYAPP_STATE_HOME", p(home, "state-env"))
+	t.Setenv("MYAPP_CONFIG_HOME", "")
+
+	newDirs := func(includeSystem bool) *toolpaths.PlatformDirs {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:               "myapp",
+			Platform:              toolpaths.PlatformLinux,
+			EnvPrefix:             "MYAPP",
+			HomeEnvIncludesSystem: includeSystem,
+		})
+		require.NoError(t, err)
+		return dirs
+	}
+
+	dirs := newDirs(false)
+	assert.Equal(t, p(root, "config"), dirs.UserConfigDir())
+	assert.Equal(t, p(root, "data"), dirs.UserDataDir())
+	assert.Equal(t, p(root, "cache"), dirs.UserCacheDir())
+	assert.Equal(t, p(home, "state-env"), dirs.UserStateDir(), "per-kind override wins")
+	assert.Equal(t, p(root, "log"), dirs.UserLogDir())
+	rt, err := dirs.UserRuntimeDir()
+	require.NoError(t, err)
+	assert.Equal(t, p(root, "runtime"), rt)
+	assert.Equal(t, p("/var", "lib", "myapp"), dirs.SystemStateDir(), "system directories unaffected")
+
+	x, err := dirs.Explain("user-cache")
+	require.NoError(t, err)
+	step, ok := x.Chosen()
+	require.True(t, ok)
+	assert.Equal(t, toolpaths.StrategyHomeEnv, step.Strategy)
+	assert.Equal(t, "MYAPP_HOME", step.EnvVar)
+	assert.Equal(t, x.Path, step.Path)
+
+	t.Run("including system directories", func(t *testing.T) {
+		dirs := newDirs(true)
+		assert.Equal(t, []string{p(root, "system", "config")}, dirs.SystemConfigDirs())
+		assert.Equal(t, p(root, "system", "state"), dirs.SystemStateDir())
+		assert.Equal(t, p(root, "system", "runtime"), dirs.SystemRuntimeDir())
+		assert.Equal(t, p("/etc", "myapp", "managed", "required"), dirs.ManagedRequiredConfigDir(), "managed never moves")
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv("MYAPP_HOME", "")
+		assert.Equal(t, p(home, ".config", "myapp"), newDirs(false).UserConfigDir())
+	})
+}
@@ -0,0 +1,538 @@
+package toolpaths
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ErrUnknownKind is returned by Explain for an unrecognized directory kind.
+var ErrUnknownKind = errors.New("toolpaths: unknown directory kind")
+
+// Strategy identifies one step in the resolution of a directory.
+type Strategy string
+
+// Resolution strategies, in the order they are considered.
+const (
+	// StrategyEnvOverride uses the app-specific variable from EnvOverrides.
+	StrategyEnvOverride Strategy = "env-override"
+
+	// StrategyHomeEnv uses the single root named by Config.HomeEnv.
+	StrategyHomeEnv Strategy = "home-env"
+
+	// StrategyPortable uses the portable tree next to the executable. It is
+	// considered only for user directories when portable mode is enabled.
+	StrategyPortable Strategy = "portable"
+
+	// StrategySystemd uses a systemd service directory variable
+	// (CONFIGURATION_DIRECTORY, STATE_DIRECTORY, ...). It is considered
+	// only for the directories selected by Config.Systemd.
+	StrategySystemd Strategy = "systemd"
+
+	// StrategySandbox uses the Flatpak or Snap conventions reported by
+	// PlatformDirs.Sandbox.
+	StrategySandbox Strategy = "sandbox"
+
+	// StrategyXDGEnv uses an XDG base directory variable (XDG_CONFIG_HOME,
+	// XDG_CONFIG_DIRS, XDG_RUNTIME_DIR, ...).
+	StrategyXDGEnv Strategy = "xdg-env"
+
+	// StrategyXDGDefault uses the XDG default location (e.g., ~/.config).
+	// It applies on XDG platforms and when XDGOnAllPlatforms is set.
+	StrategyXDGDefault Strategy = "xdg-default"
+
+	// StrategyNative uses the platform convention: FHS on Linux/BSD,
+	// ~/Library and /Library on macOS, Known Folders on Windows.
+	StrategyNative Strategy = "native"
+
+	// StrategyTempDir uses the temporary directory when no runtime
+	// directory is configured.
+	StrategyTempDir Strategy = "temp-dir"
+
+	// StrategyXDGFallback is the XDG default listed after the primary
+	// directory by User*Dirs on non-XDG platforms. It never wins; it is a
+	// read-only fallback for migration.
+	StrategyXDGFallback Strategy = "xdg-fallback"
+
+	// StrategyPreviousVersion is the newest older version's directory,
+	// listed after the primary directory by User*Dirs when
+	// Config.VersionFallback is set. It never wins.
+	StrategyPreviousVersion Strategy = "previous-version"
+
+	// StrategyLegacy is a pre-XDG location from Config.LegacyPaths, listed
+	// last by User*Dirs. Like StrategyXDGFallback, it never wins.
+	StrategyLegacy Strategy = "legacy"
+)
+
+// ResolutionStep records one strategy considered while resolving a directory.
+type ResolutionStep struct {
+	Strategy Strategy
+
+	// EnvVar is the environment variable this step consulted, if any.
+	EnvVar string
+
+	// Value is the input this step saw: the EnvVar value, the home
+	// directory, or the platform folder the path is built on.
+	Value string
+
+	// Path is the directory this step produces. Empty if the step does not
+	// apply; Note says why.
+	Path string
+
+	// Note explains why the step did or did not apply.
+	Note string
+
+	// Chosen is true for the step whose Path was used.
+	Chosen bool
+}
+
+// Explanation describes how a directory kind was resolved.
+type Explanation struct {
+	// Kind names the directory type, as in DirInfo.Kind.
+	Kind string
+
+	// Path is the resolved directory, as returned by the matching *Dir method.
+	Path string
+
+	// Paths is the full search path for kinds that have one (the matching
+	// *Dirs method); otherwise it holds just Path.
+	Paths []string
+
+	// Err is the resolution error, if any.
+	Err error
+
+	// Steps lists every strategy considered, in precedence order.
+	Steps []ResolutionStep
+}
+
+// Chosen returns the step that produced Path.
+func (x Explanation) Chosen() (ResolutionStep, bool) {
+	for _, step := range x.Steps {
+		if step.Chosen {
+			return step, true
+		}
+	}
+	return ResolutionStep{}, false
+}
+
+// Kinds returns the directory kind names accepted by Explain, in the
+// order ExplainAll reports them.
+func Kinds() []string {
+	kinds := make([]string, 0, int(managedRecommended)+1)
+	for dt := userConfig; dt <= managedRecommended; dt++ {
+		kinds = append(kinds, dt.String())
+	}
+	return kinds
+}
+
+// Explain reports the chain of strategies considered when resolving kind
+// (e.g., "user-config"; see Kinds), the values each one saw, and which one
+// won. Returns ErrUnknownKind for unrecognized kinds.
+func (d *PlatformDirs) Explain(kind string) (Explanation, error) {
+	for dt := userConfig; dt <= managedRecommended; dt++ {
+		if dt.String() == kind {
+			return d.explain(dt), nil
+		}
+	}
+	return Explanation{}, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+}
+
+// ExplainAll returns an Explanation for every directory kind.
+func (d *PlatformDirs) ExplainAll() []Explanation {
+	all := make([]Explanation, 0, int(managedRecommended)+1)
+	for dt := userConfig; dt <= managedRecommended; dt++ {
+		all = append(all, d.explain(dt))
+	}
+	return all
+}
+
+// ---------------------------------------------------------------------
+// Internal: explanation builders
+// ---------------------------------------------------------------------
+
+// explainer accumulates steps; the first step with a path wins.
+type explainer struct {
+	steps  []ResolutionStep
+	chosen bool
+}
+
+func (e *explainer) add(step ResolutionStep) {
+	readOnly := step.Strategy == StrategyXDGFallback || step.Strategy == StrategyPreviousVersion ||
+		step.Strategy == StrategyLegacy
+	if !e.chosen && step.Path != "" && !readOnly {
+		step.Chosen = true
+		e.chosen = true
+	}
+	e.steps = append(e.steps, step)
+}
+
+func (d *PlatformDirs) explain(dt dirType) Explanation {
+	e := &explainer{}
+	if dt != managedRequired {
+		e.add(d.explainEnvOverride(dt))
+	}
+	if d.cfg.HomeEnv != "" && rootSubdir(dt) != "" {
+		e.add(d.explainHomeEnv(dt))
+	}
+	if d.portable != "" && dt <= userRuntime {
+		e.add(ResolutionStep{
+			Strategy: StrategyPortable,
+			Value:    d.portable,
+			Path:     d.portableDir(dt),
+			Note:     "portable mode, relative to the portable root",
+		})
+	}
+	if d.systemdEnv(dt) != "" {
+		e.add(d.explainSystemd(dt))
+	}
+	if d.sandbox.Kind != SandboxNone {
+		e.add(d.explainSandbox(dt))
+	}
+
+	switch dt {
+	case userConfig, userData, userCache, userState, userLog:
+		d.explainUserDir(e, dt)
+	case userRuntime:
+		d.explainRuntimeDir(e)
+	case systemConfig, systemData:
+		d.explainSystemDirs(e, dt)
+	case systemCache, systemState, systemLog, systemRuntime:
+		e.add(d.explainNative(dt, d.nativeSystemSingleDir(dt)))
+	case managedRequired, managedRecommended:
+		e.add(d.explainNative(dt, d.resolveManagedDir(dt)))
+	}
+
+	x := Explanation{Kind: dt.String(), Steps: e.steps}
+	x.Paths, x.Err = d.resolvedPaths(dt)
+	if len(x.Paths) > 0 {
+		x.Path = x.Paths[0]
+	}
+	return x
+}
+
+// resolvedPaths returns the result of the public method for dt, so an
+// Explanation always agrees with what callers actually get.
+func (d *PlatformDirs) resolvedPaths(dt dirType) ([]string, error) {
+	switch dt {
+	case userConfig, userData, userCache, userState, userLog:
+		return d.userDirsWithFallbacks(dt), nil
+	case userRuntime:
+		dir, err := d.UserRuntimeDir()
+		if err != nil {
+			return nil, err
+		}
+		return []string{dir}, nil
+	case systemConfig:
+		return d.SystemConfigDirs(), nil
+	case systemData:
+		return d.SystemDataDirs(), nil
+	case systemCache:
+		return singlePath(d.SystemCacheDir()), nil
+	case systemState:
+		return singlePath(d.SystemStateDir()), nil
+	case systemLog:
+		return singlePath(d.SystemLogDir()), nil
+	case systemRuntime:
+		return singlePath(d.SystemRuntimeDir()), nil
+	case managedRequired:
+		return singlePath(d.ManagedRequiredConfigDir()), nil
+	case managedRecommended:
+		return singlePath(d.ManagedRecommendedConfigDir()), nil
+	default:
+		return nil, nil
+	}
+}
+
+func singlePath(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	return []string{dir}
+}
+
+func (d *PlatformDirs) explainEnvOverride(dt dirType) ResolutionStep {
+	step := ResolutionStep{Strategy: StrategyEnvOverride}
+	if d.cfg.EnvOverrides != nil {
+		step.EnvVar = d.cfg.EnvOverrides.get(dt)
+	}
+	switch {
+	case step.EnvVar == "":
+		step.Note = "no EnvOverrides variable configured"
+	case d.getenv(step.EnvVar) == "":
+		step.Note = step.EnvVar + " is unset or empty"
+	default:
+		step.Value = d.getenv(step.EnvVar)
+		step.Path = d.fromKindEnv(dt)
+		if d.cfg.EnvOverrides.AppendAppName {
+			step.Note = "app-specific override, with app path appended"
+		} else {
+			step.Note = "app-specific override, used as-is"
+		}
+	}
+	return step
+}
+
+func (d *PlatformDirs) explainHomeEnv(dt dirType) ResolutionStep {
+	step := ResolutionStep{Strategy: StrategyHomeEnv, EnvVar: d.cfg.HomeEnv}
+	step.Value = d.getenv(step.EnvVar)
+	switch {
+	case step.Value == "":
+		step.Note = step.EnvVar + " is unset or empty"
+	case dt > userRuntime && !d.cfg.HomeEnvIncludesSystem:
+		step.Note = "HomeEnvIncludesSystem is false"
+	default:
+		step.Path = d.homeEnvDir(dt)
+		step.Note = "single-root override, used as-is"
+	}
+	return step
+}
+
+func (d *PlatformDirs) explainSystemd(dt dirType) ResolutionStep {
+	step := ResolutionStep{Strategy: StrategySystemd, EnvVar: d.systemdEnv(dt)}
+	step.Value = d.getenv(step.EnvVar)
+	dirs := d.systemdDirs(dt)
+	switch {
+	case step.Value == "":
+		step.Note = step.EnvVar + " is unset or empty"
+	case d.fromEnvOverride(dt) != "":
+		step.Note = "an app-specific variable takes precedence"
+	case d.portableDir(dt) != "":
+		step.Note = "portable mode takes precedence"
+	case len(dirs) == 0:
+		step.Note = "no absolute paths in " + step.EnvVar
+	default:
+		step.Path = dirs[0]
+		step.Note = fmt.Sprintf("systemd service directory, first of %d", len(dirs))
+	}
+	return step
+}
+
+func (d *PlatformDirs) explainSandbox(dt dirType) ResolutionStep {
+	step := ResolutionStep{Strategy: StrategySandbox, Value: d.sandbox.ID}
+	if dirs := d.sandboxDirs(dt); len(dirs) > 0 {
+		step.Path = dirs[0]
+		step.Note = d.sandbox.Kind.String() + " sandbox convention"
+	} else {
+		step.Note = "no " + d.sandbox.Kind.String() + " convention for this directory"
+	}
+	return step
+}
+
+// xdgUserEnvVar returns the XDG variable consulted for a user directory.
+// The log directory derives from XDG_STATE_HOME.
+func xdgUserEnvVar(dt dirType) string {
+	switch dt { //nolint:exhaustive // only user dir types are supported
+	case userConfig:
+		return "XDG_CONFIG_HOME"
+	case userData:
+		return "XDG_DATA_HOME"
+	case userCache:
+		return "XDG_CACHE_HOME"
+	case userState, userLog:
+		return "XDG_STATE_HOME"
+	default:
+		return ""
+	}
+}
+
+// xdgPrimary reports whether XDG conventions are the primary strategy.
+func (d *PlatformDirs) xdgPrimary() bool {
+	return d.isXDGPlatform() || d.cfg.XDGOnAllPlatforms
+}
+
+// xdgPrimaryNote explains why XDG conventions are or are not primary.
+func (d *PlatformDirs) xdgPrimaryNote() string {
+	switch {
+	case d.isXDGPlatform():
+		return "XDG is native on " + d.platform.String()
+	case d.cfg.XDGOnAllPlatforms:
+		return "XDGOnAllPlatforms is true"
+	default:
+		return "XDGOnAllPlatforms is false"
+	}
+}
+
+// explainUserDir mirrors resolveUserDir and userDirsWithFallbacks.
+func (d *PlatformDirs) explainUserDir(e *explainer, dt dirType) {
+	envVar := xdgUserEnvVar(dt)
+	xdgEnv := ResolutionStep{Strategy: StrategyXDGEnv, EnvVar: envVar, Value: d.getenv(envVar)}
+	switch {
+	case xdgEnv.Value != "" && d.xdgEnv(envVar) == "":
+		xdgEnv.Note = "ignored: relative path (StrictXDG)"
+	case xdgEnv.Value != "":
+		xdgEnv.Path = d.xdgUserDirEnvOnly(dt)
+		xdgEnv.Note = envVar + " is set; respected on every platform"
+	default:
+		xdgEnv.Note = envVar + " is unset or empty"
+	}
+	e.add(xdgEnv)
+
+	xdgDefault := ResolutionStep{Strategy: StrategyXDGDefault, Value: d.homeDir(), Note: d.xdgPrimaryNote()}
+	if d.xdgPrimary() {
+		xdgDefault.Path = d.xdgUserDirDefault(dt)
+	}
+	e.add(xdgDefault)
+
+	native := ResolutionStep{Strategy: StrategyNative}
+	switch {
+	case d.xdgPrimary():
+		native.Note = "not used: " + d.xdgPrimaryNote()
+	case d.platform == PlatformMacOS:
+		native.Value = filepath.Join(d.homeDir(), "Library")
+		native.Path = d.macOSUserDir(dt)
+		native.Note = "macOS ~/Library convention"
+	case d.platform == PlatformWindows:
+		native.Value, native.Note = d.windowsUserBase(dt)
+		native.Path = d.windowsUserDir(dt)
+	}
+	e.add(native)
+
+	if d.cfg.VersionFallback && d.cfg.Version != "" {
+		prev := ResolutionStep{Strategy: StrategyPreviousVersion, Value: d.cfg.Version}
+		if prev.Path = d.previousVersionDir(dt); prev.Path != "" {
+			prev.Note = "read-only fallback to the newest older version (VersionFallback)"
+		} else {
+			prev.Note = "not used: no older version directory exists"
+		}
+		e.add(prev)
+	}
+
+	fallback := ResolutionStep{Strategy: StrategyXDGFallback}
+	dirs := d.platformUserDirs(dt)
+	switch {
+	case len(dirs) > 1:
+		fallback.Path = dirs[1]
+		fallback.Note = "read-only fallback listed by User*Dirs for migration"
+	case d.xdgPrimary():
+		fallback.Note = "not used: " + d.xdgPrimaryNote()
+	case !d.includeXDGFallbacks():
+		fallback.Note = "not used: IncludeXDGFallbacks is false"
+	default:
+		fallback.Note = "not used: same as primary directory"
+	}
+	e.add(fallback)
+
+	for _, dir := range d.legacyDirs(dt) {
+		e.add(ResolutionStep{
+			Strategy: StrategyLegacy,
+			Path:     dir,
+			Note:     "read-only legacy location from Config.LegacyPaths, listed last by User*Dirs",
+		})
+	}
+}
+
+// windowsUserBase returns the Known Folder a Windows user directory is
+// built on, and a note naming it.
+func (d *PlatformDirs) windowsUserBase(dt dirType) (string, string) {
+	if (dt == userConfig || dt == userData || dt == userState) && d.cfg.Roaming {
+		return d.windowsRoamingAppData(), "Windows FOLDERID_RoamingAppData (Roaming is true)"
+	}
+	return d.windowsLocalAppData(), "Windows FOLDERID_LocalAppData"
+}
+
+// explainRuntimeDir mirrors resolveRuntimeDir.
+func (d *PlatformDirs) explainRuntimeDir(e *explainer) {
+	xdgEnv := ResolutionStep{Strategy: StrategyXDGEnv, EnvVar: "XDG_RUNTIME_DIR", Value: d.getenv("XDG_RUNTIME_DIR")}
+	switch {
+	case xdgEnv.Value != "" && d.xdgEnv("XDG_RUNTIME_DIR") == "":
+		xdgEnv.Note = "ignored: relative path (StrictXDG)"
+	case xdgEnv.Value != "":
+		xdgEnv.Path = filepath.Join(xdgEnv.Value, d.appPath(userRuntime))
+		xdgEnv.Note = "XDG_RUNTIME_DIR is set; respected on every platform"
+	default:
+		xdgEnv.Note = "XDG_RUNTIME_DIR is unset or empty"
+	}
+	e.add(xdgEnv)
+
+	switch d.platform { //nolint:exhaustive // PlatformAuto resolved during construction
+	case PlatformLinux, PlatformFreeBSD, PlatformOpenBSD:
+		e.add(ResolutionStep{
+			Strategy: StrategyTempDir,
+			EnvVar:   "TMPDIR",
+			Value:    d.tempDir(),
+			Path:     d.tempRuntimeDir(),
+			Note:     "per-user temp directory; unlike XDG_RUNTIME_DIR it persists across logins",
+		})
+	case PlatformMacOS:
+		e.add(ResolutionStep{
+			Strategy: StrategyTempDir,
+			EnvVar:   "TMPDIR",
+			Value:    d.tempDir(),
+			Path:     filepath.Join(d.tempDir(), d.appPath(userRuntime)),
+			Note:     "$TMPDIR is per-user on macOS",
+		})
+	case PlatformWindows:
+		e.add(ResolutionStep{
+			Strategy: StrategyNative,
+			Value:    d.windowsLocalAppData(),
+			Path:     filepath.Join(d.windowsLocalAppData(), d.windowsAppPath(userRuntime), "runtime"),
+			Note:     "Windows FOLDERID_LocalAppData",
+		})
+	}
+}
+
+// explainSystemDirs mirrors resolveSystemDirs.
+func (d *PlatformDirs) explainSystemDirs(e *explainer, dt dirType) {
+	envVar, defaultVal := "XDG_CONFIG_DIRS", "/etc/xdg"
+	if dt == systemData {
+		envVar, defaultVal = "XDG_DATA_DIRS", "/usr/local/share:/usr/share"
+	}
+
+	xdgEnv := ResolutionStep{Strategy: StrategyXDGEnv, EnvVar: envVar, Value: d.getenv(envVar)}
+	if xdgEnv.Value != "" {
+		if dirs := d.xdgSystemDirsEnvOnly(dt); len(dirs) > 0 {
+			xdgEnv.Path = dirs[0]
+			xdgEnv.Note = envVar + " is set; respected on every platform"
+		} else {
+			xdgEnv.Note = "ignored: no usable entries"
+		}
+	} else {
+		xdgEnv.Note = envVar + " is unset or empty"
+	}
+	e.add(xdgEnv)
+
+	xdgDefault := ResolutionStep{Strategy: StrategyXDGDefault, Value: defaultVal, Note: d.xdgPrimaryNote()}
+	switch {
+	case !d.xdgPrimary():
+	case xdgEnv.Value != "":
+		xdgDefault.Note = "not used: " + envVar + " is set"
+	default:
+		if dirs := d.xdgSystemDirs(dt); len(dirs) > 0 {
+			xdgDefault.Path = dirs[0]
+		}
+	}
+	e.add(xdgDefault)
+
+	native := ResolutionStep{Strategy: StrategyNative}
+	switch {
+	case d.xdgPrimary():
+		native.Note = "not used: " + d.xdgPrimaryNote()
+	case d.platform == PlatformMacOS:
+		native.Path = d.macOSSystemDirs(dt)[0]
+		native.Note = "macOS /Library convention"
+	case d.platform == PlatformWindows:
+		native.Value = d.windowsProgramData()
+		native.Path = d.windowsSystemDirs(dt)[0]
+		native.Note = "Windows FOLDERID_ProgramData"
+	}
+	e.add(native)
+}
+
+// explainNative describes a single platform-native location.
+func (d *PlatformDirs) explainNative(dt dirType, dir string) ResolutionStep {
+	step := ResolutionStep{Strategy: StrategyNative, Path: dir}
+	switch {
+	case dir == "":
+		step.Note = "no equivalent on " + d.platform.String()
+	case d.platform == PlatformWindows:
+		step.Value = d.windowsProgramData()
+		step.Note = "Windows FOLDERID_ProgramData"
+	case d.platform == PlatformMacOS:
+		step.Note = "macOS /Library convention"
+	case dt == managedRequired || dt == managedRecommended:
+		step.Note = "managed policy under /etc"
+	default:
+		step.Note = "Filesystem Hierarchy Standard"
+	}
+	return step
+}
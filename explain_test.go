@@ -0,0 +1,172 @@
+package toolpaths_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func strategies(x toolpaths.Explanation) []toolpaths.Strategy {
+	var s []toolpaths.Strategy
+	for _, step := range x.Steps {
+		s = append(s, step.Strategy)
+	}
+	return s
+}
+
+func TestExplainChosenMatchesResolution(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("XDG_CONFIG_DIRS", "")
+	t.Setenv("XDG_DATA_DIRS", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+
+	configs := map[string]toolpaths.Config{
+		"linux":       {Platform: toolpaths.PlatformLinux},
+		"macos":       {Platform: toolpaths.PlatformMacOS},
+		"windows":     {Platform: toolpaths.PlatformWindows, AppAuthor: "Acme", Roaming: true},
+		"macos-xdg":   {Platform: toolpaths.PlatformMacOS, XDGOnAllPlatforms: true},
+		"windows-xdg": {Platform: toolpaths.PlatformWindows, XDGOnAllPlatforms: true},
+	}
+	envs := map[string]map[string]string{
+		"defaults": {},
+		"xdg env": {
+			"XDG_CONFIG_HOME": filepath.Join(home, "xdg-config"),
+			"XDG_STATE_HOME":  filepath.Join(home, "xdg-state"),
+			"XDG_CONFIG_DIRS": filepath.Join(home, "etc-a") + ":" + filepath.Join(home, "etc-b"),
+			"XDG_RUNTIME_DIR": filepath.Join(home, "run"),
+		},
+		"overrides": {
+			"TESTAPP_CONFIG":  filepath.Join(home, "override"),
+			"TESTAPP_SYSTEM":  filepath.Join(home, "sys-override"),
+			"TESTAPP_RUNTIME": filepath.Join(home, "rt-override"),
+		},
+	}
+
+	for cfgName, cfg := range configs {
+		for envName, env := range envs {
+			t.Run(cfgName+"/"+envName, func(t *testing.T) {
+				for k, v := range env {
+					t.Setenv(k, v)
+				}
+				cfg.AppName = "testapp"
+				cfg.EnvOverrides = &toolpaths.EnvOverrides{
+					UserConfig:    "TESTAPP_CONFIG",
+					SystemConfig:  "TESTAPP_SYSTEM",
+					UserRuntime:   "TESTAPP_RUNTIME",
+					AppendAppName: true,
+				}
+				dirs, err := toolpaths.NewWithConfig(cfg)
+				require.NoError(t, err)
+
+				for _, x := range dirs.ExplainAll() {
+					chosen, ok := x.Chosen()
+					if x.Path == "" {
+						assert.False(t, ok, "%s: no step should win for an empty path", x.Kind)
+						continue
+					}
+					require.True(t, ok, "%s: a step should win", x.Kind)
+					assert.Equal(t, x.Path, chosen.Path, "%s via %s", x.Kind, chosen.Strategy)
+				}
+			})
+		}
+	}
+}
+
+func TestExplainUserConfig(t *testing.T) {
+	home := setTestHomeXDG(t)
+
+	t.Run("linux default", func(t *testing.T) {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "testapp", Platform: toolpaths.PlatformLinux})
+		require.NoError(t, err)
+
+		x, err := dirs.Explain("user-config")
+		require.NoError(t, err)
+		assert.Equal(t, "user-config", x.Kind)
+		assert.Equal(t, dirs.UserConfigDir(), x.Path)
+		assert.Equal(t, []toolpaths.Strategy{
+			toolpaths.StrategyEnvOverride,
+			toolpaths.StrategyXDGEnv,
+			toolpaths.StrategyXDGDefault,
+			toolpaths.StrategyNative,
+			toolpaths.StrategyXDGFallback,
+		}, strategies(x))
+
+		chosen, ok := x.Chosen()
+		require.True(t, ok)
+		assert.Equal(t, toolpaths.StrategyXDGDefault, chosen.Strategy)
+		assert.Equal(t, home, chosen.Value)
+		assert.Equal(t, "XDG_CONFIG_HOME", x.Steps[1].EnvVar)
+	})
+
+	t.Run("macos with XDG env", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "testapp", Platform: toolpaths.PlatformMacOS})
+		require.NoError(t, err)
+
+		x, err := dirs.Explain("user-config")
+		require.NoError(t, err)
+		chosen, _ := x.Chosen()
+		assert.Equal(t, toolpaths.StrategyXDGEnv, chosen.Strategy)
+		assert.Equal(t, filepath.Join(home, "xdg"), chosen.Value)
+		assert.Equal(t, filepath.Join(home, "xdg", "testapp"), x.Path)
+
+		// The native path is still reported, but does not win.
+		native := x.Steps[3]
+		assert.Equal(t, toolpaths.StrategyNative, native.Strategy)
+		assert.Equal(t, filepath.Join(home, "Library", "Application Support", "testapp"), native.Path)
+		assert.False(t, native.Chosen)
+	})
+
+	t.Run("macos fallback", func(t *testing.T) {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "testapp", Platform: toolpaths.PlatformMacOS})
+		require.NoError(t, err)
+
+		x, err := dirs.Explain("user-config")
+		require.NoError(t, err)
+		fallback := x.Steps[4]
+		assert.Equal(t, toolpaths.StrategyXDGFallback, fallback.Strategy)
+		assert.Equal(t, filepath.Join(home, ".config", "testapp"), fallback.Path)
+		assert.False(t, fallback.Chosen)
+		assert.Equal(t, dirs.UserConfigDirs(), x.Paths)
+	})
+}
+
+func TestExplainRuntimeTempFallback(t *testing.T) {
+	setTestHomeXDG(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "testapp", Platform: toolpaths.PlatformLinux})
+	require.NoError(t, err)
+
+	x, err := dirs.Explain("user-runtime")
+	require.NoError(t, err)
+	chosen, ok := x.Chosen()
+	require.True(t, ok)
+	assert.Equal(t, toolpaths.StrategyTempDir, chosen.Strategy)
+	assert.NotEmpty(t, chosen.Note)
+}
+
+func TestExplainUnknownKind(t *testing.T) {
+	dirs, err := toolpaths.New("testapp")
+	require.NoError(t, err)
+
+	_, err = dirs.Explain("user-music")
+	require.ErrorIs(t, err, toolpaths.ErrUnknownKind)
+}
+
+func TestKinds(t *testing.T) {
+	kinds := toolpaths.Kinds()
+	assert.Equal(t, "user-config", kinds[0])
+	assert.Contains(t, kinds, "system-runtime")
+	assert.Contains(t, kinds, "managed-recommended")
+
+	dirs, err := toolpaths.New("testapp")
+	require.NoError(t, err)
+	all := dirs.ExplainAll()
+	require.Len(t, all, len(kinds))
+	for i, x := range all {
+		assert.Equal(t, kinds[i], x.Kind)
+	}
+}
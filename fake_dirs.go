@@ -1,6 +1,7 @@
 package toolpaths
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 )
@@ -33,6 +34,19 @@ type FakeDirs struct {
 	UserStateDirsVal  []string
 	UserLogDirsVal    []string
 
+	// User media directories
+	UserDesktopDirVal   string
+	UserDocumentsDirVal string
+	UserDownloadDirVal  string
+	UserMusicDirVal     string
+	UserPicturesDirVal  string
+	UserVideosDirVal    string
+	UserTemplatesDirVal string
+	UserPublicDirVal    string
+
+	// HomeDirVal is the home directory FindUpOptions.StopAtHome stops at.
+	HomeDirVal string
+
 	// System directories
 	SystemConfigDirsVal []string
 	SystemDataDirsVal   []string
@@ -41,8 +55,17 @@ type FakeDirs struct {
 	SystemLogDirVal     string
 	SystemRuntimeDirVal string
 
+	// Managed policy directories
+	ManagedRequiredDirVal    string
+	ManagedRecommendedDirVal string
+
+	// FS, if set, backs existence checks, FindUp* traversal, and Ensure*
+	// directory creation, and takes precedence over ExistingFiles. Use a
+	// MapFS to model files, directories, contents, and modes in memory.
+	FS FS
+
 	// ExistingFiles maps paths to existence. Used by Find* and Existing* methods.
-	// If nil, file existence checks use the real filesystem.
+	// If nil (and FS is nil), file existence checks use the real filesystem.
 	// If non-nil, only paths in this map with true values are considered to exist.
 	ExistingFiles map[string]bool
 
@@ -52,12 +75,18 @@ type FakeDirs struct {
 
 	// CreateDirs controls whether Ensure* methods actually create directories.
 	// If false (default), Ensure* methods just return the path (and any configured error).
-	// If true, Ensure* methods call os.MkdirAll.
+	// If true, Ensure* methods call MkdirAll on FS, or os.MkdirAll if FS is nil.
 	CreateDirs bool
 }
 
-// Compile-time check that FakeDirs implements Dirs.
-var _ Dirs = (*FakeDirs)(nil)
+// Compile-time checks that FakeDirs implements Dirs and its extensions.
+var (
+	_ Dirs          = (*FakeDirs)(nil)
+	_ VariantFinder = (*FakeDirs)(nil)
+	_ ManagedDirs   = (*FakeDirs)(nil)
+	_ MediaDirs     = (*FakeDirs)(nil)
+	_ ContextFinder = (*FakeDirs)(nil)
+)
 
 // NewFakeDirs creates a FakeDirs with all paths set to subdirectories of the given base.
 // This is convenient for tests that need a complete, consistent fake.
@@ -76,14 +105,27 @@ func NewFakeDirs(base string) *FakeDirs {
 		UserStateHomeVal:    filepath.Join(base, "state"),
 		UserLogHomeVal:      filepath.Join(base, "log"),
 		UserRuntimeDirVal:   filepath.Join(base, "runtime"),
+		HomeDirVal:          filepath.Join(base, "home"),
+		UserDesktopDirVal:   filepath.Join(base, "home", "Desktop"),
+		UserDocumentsDirVal: filepath.Join(base, "home", "Documents"),
+		UserDownloadDirVal:  filepath.Join(base, "home", "Downloads"),
+		UserMusicDirVal:     filepath.Join(base, "home", "Music"),
+		UserPicturesDirVal:  filepath.Join(base, "home", "Pictures"),
+		UserVideosDirVal:    filepath.Join(base, "home", "Videos"),
+		UserTemplatesDirVal: filepath.Join(base, "home", "Templates"),
+		UserPublicDirVal:    filepath.Join(base, "home", "Public"),
 		SystemConfigDirsVal: []string{filepath.Join(base, "system", "config")},
 		SystemDataDirsVal:   []string{filepath.Join(base, "system", "data")},
 		SystemCacheDirVal:   filepath.Join(base, "system", "cache"),
 		SystemStateDirVal:   filepath.Join(base, "system", "state"),
 		SystemLogDirVal:     filepath.Join(base, "system", "log"),
 		SystemRuntimeDirVal: filepath.Join(base, "system", "runtime"),
-		ExistingFiles:       make(map[string]bool),
-		EnsureErrors:        make(map[string]error),
+
+		ManagedRequiredDirVal:    filepath.Join(base, "managed", "required"),
+		ManagedRecommendedDirVal: filepath.Join(base, "managed", "recommended"),
+
+		ExistingFiles: make(map[string]bool),
+		EnsureErrors:  make(map[string]error),
 	}
 }
 
@@ -125,13 +167,46 @@ func (f *FakeDirs) SetNotExisting(path string) {
 	f.ExistingFiles[path] = false
 }
 
-// fileExists checks if a path exists, using ExistingFiles map if set.
+// fileExists checks if a path exists, using FS or the ExistingFiles map if set.
 func (f *FakeDirs) fileExists(path string) bool {
+	if f.FS != nil {
+		return fsExists(f.FS, path)
+	}
+	if f.ExistingFiles != nil {
+		return f.ExistingFiles[path]
+	}
+	return fileExists(path)
+}
+
+// dirExists checks if a directory exists, using FS or the ExistingFiles map
+// if set. The map does not distinguish files from directories, so any path
+// marked as existing counts.
+func (f *FakeDirs) dirExists(path string) bool {
+	if f.FS != nil {
+		return fsDirExists(f.FS, path)
+	}
 	if f.ExistingFiles != nil {
 		return f.ExistingFiles[path]
 	}
-	_, err := os.Stat(path)
-	return err == nil
+	return dirExists(path)
+}
+
+// ensureDir implements the Ensure* methods: it returns the error configured
+// for key, or creates dir if CreateDirs is set.
+func (f *FakeDirs) ensureDir(key, dir string) (string, error) {
+	if err := f.EnsureErrors[key]; err != nil {
+		return "", err
+	}
+	if f.CreateDirs {
+		fsys := f.FS
+		if fsys == nil {
+			fsys = OSFS()
+		}
+		if err := fsys.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
 }
 
 // --- User config ---
@@ -251,6 +326,17 @@ func (f *FakeDirs) UserRuntimePath(elem ...string) (string, error) {
 	return path(base, elem...), nil
 }
 
+// --- User media ---
+
+func (f *FakeDirs) UserDesktopDir() string   { return f.UserDesktopDirVal }
+func (f *FakeDirs) UserDocumentsDir() string { return f.UserDocumentsDirVal }
+func (f *FakeDirs) UserDownloadDir() string  { return f.UserDownloadDirVal }
+func (f *FakeDirs) UserMusicDir() string     { return f.UserMusicDirVal }
+func (f *FakeDirs) UserPicturesDir() string  { return f.UserPicturesDirVal }
+func (f *FakeDirs) UserVideosDir() string    { return f.UserVideosDirVal }
+func (f *FakeDirs) UserTemplatesDir() string { return f.UserTemplatesDirVal }
+func (f *FakeDirs) UserPublicDir() string    { return f.UserPublicDirVal }
+
 // --- System config ---
 
 func (f *FakeDirs) SystemConfigDirs() []string {
@@ -328,6 +414,24 @@ func (f *FakeDirs) SystemRuntimePath(elem ...string) string {
 	return path(f.SystemRuntimeDirVal, elem...)
 }
 
+// --- Managed policy ---
+
+func (f *FakeDirs) ManagedRequiredConfigDir() string {
+	return f.ManagedRequiredDirVal
+}
+
+func (f *FakeDirs) ManagedRequiredConfigPath(elem ...string) string {
+	return path(f.ManagedRequiredDirVal, elem...)
+}
+
+func (f *FakeDirs) ManagedRecommendedConfigDir() string {
+	return f.ManagedRecommendedDirVal
+}
+
+func (f *FakeDirs) ManagedRecommendedConfigPath(elem ...string) string {
+	return path(f.ManagedRecommendedDirVal, elem...)
+}
+
 // --- Find utilities ---
 
 func (f *FakeDirs) FindConfigFile(filename string) (string, bool) {
@@ -360,6 +464,38 @@ func (f *FakeDirs) ExistingConfigFiles(filename string) []string {
 	return existing
 }
 
+func (f *FakeDirs) FindManagedConfigFile(filename string) (string, bool) {
+	for _, p := range f.AllManagedConfigPaths(filename) {
+		if f.fileExists(p) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// AllManagedConfigPaths omits managed layers whose directory is empty.
+func (f *FakeDirs) AllManagedConfigPaths(filename string) []string {
+	var paths []string
+	if f.ManagedRequiredDirVal != "" {
+		paths = append(paths, filepath.Join(f.ManagedRequiredDirVal, filename))
+	}
+	paths = append(paths, f.AllConfigPaths(filename)...)
+	if f.ManagedRecommendedDirVal != "" {
+		paths = append(paths, filepath.Join(f.ManagedRecommendedDirVal, filename))
+	}
+	return paths
+}
+
+func (f *FakeDirs) ExistingManagedConfigFiles(filename string) []string {
+	var existing []string
+	for _, p := range f.AllManagedConfigPaths(filename) {
+		if f.fileExists(p) {
+			existing = append(existing, p)
+		}
+	}
+	return existing
+}
+
 func (f *FakeDirs) FindDataFile(filename string) (string, bool) {
 	for _, p := range f.AllDataPaths(filename) {
 		if f.fileExists(p) {
@@ -510,66 +646,76 @@ func (f *FakeDirs) ExistingRuntimeFiles(filename string) []string {
 	return existing
 }
 
+// --- Variant find utilities ---
+
+func (f *FakeDirs) FindConfigFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, f.AllConfigPaths, f.fileExists)
+}
+
+func (f *FakeDirs) ExistingConfigFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, f.AllConfigPaths, f.fileExists)
+}
+
+func (f *FakeDirs) FindDataFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, f.AllDataPaths, f.fileExists)
+}
+
+func (f *FakeDirs) ExistingDataFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, f.AllDataPaths, f.fileExists)
+}
+
+func (f *FakeDirs) FindCacheFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, f.AllCachePaths, f.fileExists)
+}
+
+func (f *FakeDirs) ExistingCacheFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, f.AllCachePaths, f.fileExists)
+}
+
+func (f *FakeDirs) FindStateFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, f.AllStatePaths, f.fileExists)
+}
+
+func (f *FakeDirs) ExistingStateFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, f.AllStatePaths, f.fileExists)
+}
+
+func (f *FakeDirs) FindLogFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, f.AllLogPaths, f.fileExists)
+}
+
+func (f *FakeDirs) ExistingLogFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, f.AllLogPaths, f.fileExists)
+}
+
+func (f *FakeDirs) FindRuntimeFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, f.AllRuntimePaths, f.fileExists)
+}
+
+func (f *FakeDirs) ExistingRuntimeFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, f.AllRuntimePaths, f.fileExists)
+}
+
 // --- Ensure utilities ---
 
 func (f *FakeDirs) EnsureUserConfigDir() (string, error) {
-	if err := f.EnsureErrors["config"]; err != nil {
-		return "", err
-	}
-	if f.CreateDirs {
-		if err := os.MkdirAll(f.UserConfigHomeVal, 0o700); err != nil {
-			return "", err
-		}
-	}
-	return f.UserConfigHomeVal, nil
+	return f.ensureDir("config", f.UserConfigHomeVal)
 }
 
 func (f *FakeDirs) EnsureUserDataDir() (string, error) {
-	if err := f.EnsureErrors["data"]; err != nil {
-		return "", err
-	}
-	if f.CreateDirs {
-		if err := os.MkdirAll(f.UserDataHomeVal, 0o700); err != nil {
-			return "", err
-		}
-	}
-	return f.UserDataHomeVal, nil
+	return f.ensureDir("data", f.UserDataHomeVal)
 }
 
 func (f *FakeDirs) EnsureUserCacheDir() (string, error) {
-	if err := f.EnsureErrors["cache"]; err != nil {
-		return "", err
-	}
-	if f.CreateDirs {
-		if err := os.MkdirAll(f.UserCacheHomeVal, 0o700); err != nil {
-			return "", err
-		}
-	}
-	return f.UserCacheHomeVal, nil
+	return f.ensureDir("cache", f.UserCacheHomeVal)
 }
 
 func (f *FakeDirs) EnsureUserStateDir() (string, error) {
-	if err := f.EnsureErrors["state"]; err != nil {
-		return "", err
-	}
-	if f.CreateDirs {
-		if err := os.MkdirAll(f.UserStateHomeVal, 0o700); err != nil {
-			return "", err
-		}
-	}
-	return f.UserStateHomeVal, nil
+	return f.ensureDir("state", f.UserStateHomeVal)
 }
 
 func (f *FakeDirs) EnsureUserLogDir() (string, error) {
-	if err := f.EnsureErrors["log"]; err != nil {
-		return "", err
-	}
-	if f.CreateDirs {
-		if err := os.MkdirAll(f.UserLogHomeVal, 0o700); err != nil {
-			return "", err
-		}
-	}
-	return f.UserLogHomeVal, nil
+	return f.ensureDir("log", f.UserLogHomeVal)
 }
 
 // --- Project discovery methods ---
@@ -649,65 +795,53 @@ func (f *FakeDirs) FindAllUpUntilFunc(
 	return f.walkUp(start, markers, stopAt, match, true)
 }
 
-// walkUp is the internal traversal function for FakeDirs.
-func (f *FakeDirs) walkUp(
+// FindUpContext is FindUpUntilFunc with the limits in opts. StopAtHome
+// uses HomeDirVal.
+func (f *FakeDirs) FindUpContext(
+	ctx context.Context,
 	start string,
-	markers, stopAt []string,
-	matchFn func(string) bool,
-	collectAll bool,
-) []Match {
-	if len(markers) == 0 {
-		return nil
-	}
-
-	dir := cleanAbsPath(start)
-	var results []Match
-
-	for {
-		if match, found := f.checkMarkers(dir, markers, matchFn); found {
-			results = append(results, match)
-			if !collectAll {
-				return results
-			}
-		}
-
-		if f.shouldStop(dir, stopAt) {
-			return results
-		}
-
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			break
-		}
-		dir = parent
+	markers []string,
+	opts FindUpOptions,
+) (Match, bool, error) {
+	matches, err := f.walker().walk(ctx, start, markers, opts, false)
+	if len(matches) == 0 {
+		return Match{}, false, err
 	}
-
-	return results
+	return matches[0], true, err
 }
 
-// checkMarkers checks if any marker exists in the directory.
-func (f *FakeDirs) checkMarkers(
-	dir string,
+// FindAllUpContext is FindAllUpUntilFunc with the limits in opts.
+func (f *FakeDirs) FindAllUpContext(
+	ctx context.Context,
+	start string,
 	markers []string,
-	matchFn func(string) bool,
-) (Match, bool) {
-	for _, m := range markers {
-		markerPath := filepath.Join(dir, m)
-		if f.fileExists(markerPath) {
-			if matchFn == nil || matchFn(markerPath) {
-				return Match{Dir: dir, Marker: m}, true
-			}
-		}
-	}
-	return Match{}, false
+	opts FindUpOptions,
+) ([]Match, error) {
+	return f.walker().walk(ctx, start, markers, opts, true)
 }
 
-// shouldStop checks if any stop marker exists in the directory.
-func (f *FakeDirs) shouldStop(dir string, stopAt []string) bool {
-	for _, s := range stopAt {
-		if f.fileExists(filepath.Join(dir, s)) {
-			return true
-		}
-	}
-	return false
+// walkUp is the internal traversal function for FakeDirs.
+func (f *FakeDirs) walkUp(
+	start string,
+	markers, stopAt []string,
+	matchFn func(string) bool,
+	collectAll bool,
+) []Match {
+	opts := FindUpOptions{StopAt: stopAt, Match: matchFn}
+	matches, _ := f.walker().walk(context.Background(), start, markers, opts, collectAll)
+	return matches
+}
+
+// walker traverses FS when set, the real filesystem when neither FS nor
+// ExistingFiles is set, and only ExistingFiles (without device numbers)
+// otherwise.
+func (f *FakeDirs) walker() upWalker {
+	w := upWalker{exists: f.fileExists, home: func() string { return f.HomeDirVal }}
+	switch {
+	case f.FS != nil:
+		w.stat = f.FS.Stat
+	case f.ExistingFiles == nil:
+		w.stat = os.Stat
+	}
+	return w
 }
@@ -24,6 +24,23 @@ func p(parts ...string) string {
 	return filepath.Join(parts...)
 }
 
+// setTestHomeXDG points HOME at a temp directory and clears the XDG user
+// directory variables. Unlike setTestHome in platform_linux_test.go, it is
+// available on every OS.
+func setTestHomeXDG(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	toolpaths.SetHomeDirFunc(func() string { return home })
+	t.Cleanup(func() { toolpaths.SetHomeDirFunc(nil) })
+	return home
+}
+
 func TestFakeDirsImplementsInterface(_ *testing.T) {
 	var _ toolpaths.Dirs = (*toolpaths.FakeDirs)(nil)
 }
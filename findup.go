@@ -1,7 +1,10 @@
 package toolpaths
 
 import (
+	"context"
+	"io/fs"
 	"path/filepath"
+	"slices"
 )
 
 // Match represents a found marker during upward traversal.
@@ -98,6 +101,74 @@ func (d *PlatformDirs) FindAllUpUntilFunc(
 	return d.walkUp(start, markers, stopAt, match, true)
 }
 
+// FindUpOptions bounds the traversal of FindUpContext and FindAllUpContext.
+// The zero value walks to the filesystem root like FindUp.
+type FindUpOptions struct {
+	// StopAt and Match behave as in FindUpUntilFunc.
+	StopAt []string
+	Match  func(markerPath string) bool
+
+	// MaxDepth limits how many directories are checked, counting start;
+	// 0 means no limit. With MaxDepth 1, only start is checked, and with
+	// MaxDepth 2, start and its parent.
+	MaxDepth int
+
+	// StopAtHome checks the home directory but not above it, when start is
+	// inside it.
+	StopAtHome bool
+
+	// OneFilesystem stops before a parent on a different device than start,
+	// like git without GIT_DISCOVERY_ACROSS_FILESYSTEM. It needs device
+	// numbers from the FS, so it only applies to the real filesystem on
+	// Unix.
+	OneFilesystem bool
+
+	// Ceilings are absolute directories traversal never enters from below,
+	// like GIT_CEILING_DIRECTORIES: start itself is always checked, but a
+	// ceiling above it is not. Relative entries are ignored. Use
+	// filepath.SplitList to read them from a variable.
+	Ceilings []string
+}
+
+// FindUpContext is FindUpUntilFunc with the limits in opts. It returns
+// ctx.Err() as soon as ctx is done, even while a stat or opts.Match is
+// blocked, so a caller can bound traversal of slow or hung network and FUSE
+// mounts with a deadline. An abandoned call keeps its goroutine until it
+// returns.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+//	defer cancel()
+//	m, ok, err := dirs.FindUpContext(ctx, cwd, []string{"go.mod"}, toolpaths.FindUpOptions{
+//		StopAtHome:    true,
+//		OneFilesystem: true,
+//	})
+func (d *PlatformDirs) FindUpContext(
+	ctx context.Context,
+	start string,
+	markers []string,
+	opts FindUpOptions,
+) (Match, bool, error) {
+	matches, err := d.walker().walk(ctx, start, markers, opts, false)
+	if len(matches) == 0 {
+		return Match{}, false, err
+	}
+	return matches[0], true, err
+}
+
+// FindAllUpContext is FindAllUpUntilFunc with the limits in opts. When ctx
+// is done, it returns the matches found so far with ctx.Err(), without
+// waiting for a blocked stat or opts.Match, as FindUpContext does.
+func (d *PlatformDirs) FindAllUpContext(
+	ctx context.Context,
+	start string,
+	markers []string,
+	opts FindUpOptions,
+) ([]Match, error) {
+	return d.walker().walk(ctx, start, markers, opts, true)
+}
+
 // walkUp is the internal traversal function. It walks from start toward the
 // filesystem root, checking for markers in each directory.
 func (d *PlatformDirs) walkUp(
@@ -106,33 +177,168 @@ func (d *PlatformDirs) walkUp(
 	matchFn func(string) bool,
 	collectAll bool,
 ) []Match {
+	opts := FindUpOptions{StopAt: stopAt, Match: matchFn}
+	matches, _ := d.walker().walk(context.Background(), start, markers, opts, collectAll)
+	return matches
+}
+
+func (d *PlatformDirs) walker() upWalker {
+	return upWalker{exists: d.fileExists, stat: d.fsys.Stat, home: d.homeDir}
+}
+
+// upWalker is the traversal shared by PlatformDirs and FakeDirs.
+type upWalker struct {
+	exists func(path string) bool
+
+	// stat reports device numbers for OneFilesystem; nil disables it.
+	stat func(path string) (fs.FileInfo, error)
+
+	// home returns where StopAtHome stops; "" disables it.
+	home func() string
+
+	// match is FindUpOptions.Match; nil accepts every existing marker.
+	match func(markerPath string) bool
+}
+
+// walk climbs from start toward the filesystem root, collecting the
+// directories that contain a marker, until a limit in opts applies.
+func (w upWalker) walk(
+	ctx context.Context,
+	start string,
+	markers []string,
+	opts FindUpOptions,
+	collectAll bool,
+) ([]Match, error) {
 	if len(markers) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	dir := cleanAbsPath(start)
+	home := ""
+	if opts.StopAtHome {
+		if h := w.home(); filepath.IsAbs(h) {
+			home = filepath.Clean(h)
+		}
+	}
+	var ceilings []string
+	for _, c := range opts.Ceilings {
+		if filepath.IsAbs(c) {
+			ceilings = append(ceilings, filepath.Clean(c))
+		}
+	}
+	var ctxErr error
+	w.match = opts.Match
+	w = w.bound(ctx, &ctxErr)
+	dev, hasDev := uint64(0), false
+	if opts.OneFilesystem {
+		dev, hasDev = w.device(dir)
+	}
+
 	var results []Match
+	for depth := 1; ; depth++ {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
 
-	for {
-		if match, found := d.checkMarkers(dir, markers, matchFn); found {
+		match, found := w.checkMarkers(dir, markers)
+		if ctxErr != nil {
+			return results, ctxErr
+		}
+		if found {
 			results = append(results, match)
 			if !collectAll {
-				return results
+				return results, nil
 			}
 		}
 
-		if shouldStop(dir, stopAt) {
-			return results
+		stop := w.shouldStop(dir, opts.StopAt)
+		if ctxErr != nil {
+			return results, ctxErr
+		}
+		if stop || dir == home || (opts.MaxDepth > 0 && depth >= opts.MaxDepth) {
+			return results, nil
 		}
 
 		parent := filepath.Dir(dir)
-		if parent == dir {
-			break
+		if parent == dir || slices.Contains(ceilings, parent) {
+			return results, nil
+		}
+		if hasDev {
+			parentDev, ok := w.device(parent)
+			if ctxErr != nil {
+				return results, ctxErr
+			}
+			if !ok || parentDev != dev {
+				return results, nil
+			}
 		}
 		dir = parent
 	}
+}
+
+// bound returns a copy of w whose exists, stat, and match calls return as
+// soon as ctx is done, reporting no file or no match and setting *errp to
+// ctx.Err(). The abandoned call finishes in its own goroutine. Without a Done channel, as
+// for context.Background, w is returned unchanged.
+func (w upWalker) bound(ctx context.Context, errp *error) upWalker {
+	if ctx.Done() == nil {
+		return w
+	}
+	wait := func(f func()) bool {
+		if *errp == nil {
+			*errp = ctx.Err()
+		}
+		if *errp != nil {
+			return false
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f()
+		}()
+		select {
+		case <-done:
+			return true
+		case <-ctx.Done():
+			*errp = ctx.Err()
+			return false
+		}
+	}
 
-	return results
+	exists := w.exists
+	w.exists = func(path string) bool {
+		var ok bool
+		return wait(func() { ok = exists(path) }) && ok
+	}
+	if stat := w.stat; stat != nil {
+		w.stat = func(path string) (fs.FileInfo, error) {
+			var fi fs.FileInfo
+			var err error
+			if !wait(func() { fi, err = stat(path) }) {
+				return nil, *errp
+			}
+			return fi, err
+		}
+	}
+	if match := w.match; match != nil {
+		w.match = func(path string) bool {
+			var ok bool
+			return wait(func() { ok = match(path) }) && ok
+		}
+	}
+	return w
+}
+
+// device returns the device number of path, if the FS reports one.
+func (w upWalker) device(path string) (uint64, bool) {
+	if w.stat == nil {
+		return 0, false
+	}
+	fi, err := w.stat(path)
+	if err != nil {
+		return 0, false
+	}
+	return fileDevice(fi)
 }
 
 // cleanAbsPath returns a cleaned absolute path.
@@ -146,16 +352,13 @@ func cleanAbsPath(path string) string {
 	return dir
 }
 
-// checkMarkers checks if any marker exists in the directory.
-func (d *PlatformDirs) checkMarkers(
-	dir string,
-	markers []string,
-	matchFn func(string) bool,
-) (Match, bool) {
+// checkMarkers checks if any marker exists in the directory and passes
+// the match predicate.
+func (w upWalker) checkMarkers(dir string, markers []string) (Match, bool) {
 	for _, m := range markers {
 		markerPath := filepath.Join(dir, m)
-		if fileExists(markerPath) {
-			if matchFn == nil || matchFn(markerPath) {
+		if w.exists(markerPath) {
+			if w.match == nil || w.match(markerPath) {
 				return Match{Dir: dir, Marker: m}, true
 			}
 		}
@@ -164,9 +367,9 @@ func (d *PlatformDirs) checkMarkers(
 }
 
 // shouldStop checks if any stop marker exists in the directory.
-func shouldStop(dir string, stopAt []string) bool {
+func (w upWalker) shouldStop(dir string, stopAt []string) bool {
 	for _, s := range stopAt {
-		if fileExists(filepath.Join(dir, s)) {
+		if w.exists(filepath.Join(dir, s)) {
 			return true
 		}
 	}
@@ -1,10 +1,14 @@
 package toolpaths_test
 
 import (
+	"context"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -542,3 +546,169 @@ func TestFakeDirsFindAllUp(t *testing.T) {
 		assert.Equal(t, home, matches[2].Dir)
 	})
 }
+
+func TestFindUpContext(t *testing.T) {
+	base := createDirHierarchy(t, map[string]string{
+		"go.mod":                    "module outer",
+		"home/me/go.mod":            "module home",
+		"home/me/work/proj/a/b/c/x": "",
+	})
+	deep := filepath.Join(base, "home", "me", "work", "proj", "a", "b", "c")
+	home := filepath.Join(base, "home", "me")
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "testapp", HomeDir: home})
+	require.NoError(t, err)
+	ctx := t.Context()
+	markers := []string{"go.mod"}
+
+	t.Run("no limits", func(t *testing.T) {
+		matches, err := dirs.FindAllUpContext(ctx, deep, markers, toolpaths.FindUpOptions{})
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+		assert.Equal(t, home, matches[0].Dir)
+		assert.Equal(t, base, matches[1].Dir)
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		_, found, err := dirs.FindUpContext(ctx, deep, markers, toolpaths.FindUpOptions{MaxDepth: 5})
+		require.NoError(t, err)
+		assert.False(t, found, "home is the sixth directory, counting start")
+
+		m, found, err := dirs.FindUpContext(ctx, deep, markers, toolpaths.FindUpOptions{MaxDepth: 6})
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, home, m.Dir)
+
+		matches, err := dirs.FindAllUpContext(ctx, home, markers, toolpaths.FindUpOptions{MaxDepth: 1})
+		require.NoError(t, err)
+		require.Len(t, matches, 1, "MaxDepth 1 checks only start")
+		assert.Equal(t, home, matches[0].Dir)
+	})
+
+	t.Run("stop at home", func(t *testing.T) {
+		matches, err := dirs.FindAllUpContext(ctx, deep, markers, toolpaths.FindUpOptions{StopAtHome: true})
+		require.NoError(t, err)
+		require.Len(t, matches, 1, "home is checked, but not above it")
+		assert.Equal(t, home, matches[0].Dir)
+
+		outside, err := dirs.FindAllUpContext(ctx, base, markers, toolpaths.FindUpOptions{StopAtHome: true})
+		require.NoError(t, err)
+		assert.Len(t, outside, 1, "no effect outside home")
+	})
+
+	t.Run("ceilings", func(t *testing.T) {
+		opts := toolpaths.FindUpOptions{Ceilings: []string{"relative", home}}
+		_, found, err := dirs.FindUpContext(ctx, deep, markers, opts)
+		require.NoError(t, err)
+		assert.False(t, found, "a ceiling is not entered from below")
+
+		m, found, err := dirs.FindUpContext(ctx, home, markers, opts)
+		require.NoError(t, err)
+		require.True(t, found, "the start directory is always checked")
+		assert.Equal(t, home, m.Dir)
+	})
+
+	t.Run("one filesystem within a tree", func(t *testing.T) {
+		matches, err := dirs.FindAllUpContext(ctx, deep, markers, toolpaths.FindUpOptions{OneFilesystem: true})
+		require.NoError(t, err)
+		assert.NotEmpty(t, matches)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, found, err := dirs.FindUpContext(canceled, deep, markers, toolpaths.FindUpOptions{})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.False(t, found)
+	})
+
+	t.Run("canceled during traversal", func(t *testing.T) {
+		running, cancel := context.WithCancel(ctx)
+		defer cancel()
+		release := make(chan struct{})
+		defer close(release)
+		opts := toolpaths.FindUpOptions{Match: func(path string) bool {
+			if filepath.Dir(path) == home {
+				return true
+			}
+			cancel()
+			<-release // a predicate that ignores ctx is abandoned
+			return true
+		}}
+		matches, err := dirs.FindAllUpContext(running, deep, markers, opts)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Len(t, matches, 1, "matches found before cancellation are returned")
+		assert.Equal(t, home, matches[0].Dir)
+	})
+}
+
+// hangingFS blocks every Stat under mount until release is closed, like a
+// hung network mount.
+type hangingFS struct {
+	toolpaths.FS
+	mount   string
+	release chan struct{}
+}
+
+func (h hangingFS) Stat(name string) (fs.FileInfo, error) {
+	if strings.HasPrefix(name, h.mount) {
+		<-h.release
+	}
+	return h.FS.Stat(name)
+}
+
+func TestFindUpContextAbandonsBlockedStat(t *testing.T) {
+	mount := p(testBase(), "nfs")
+	hung := hangingFS{FS: toolpaths.NewMapFS(), mount: mount, release: make(chan struct{})}
+	t.Cleanup(func() { close(hung.release) })
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformLinux, FS: hung})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, found, err := dirs.FindUpContext(ctx, p(mount, "project"), []string{"go.mod"}, toolpaths.FindUpOptions{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, found)
+}
+
+func TestFindUpContextDeviceBoundary(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("needs /proc mounted on its own device")
+	}
+	root, err := os.Stat("/")
+	require.NoError(t, err)
+	proc, err := os.Stat("/proc/self")
+	if err != nil || os.SameFile(root, proc) {
+		t.Skip("/proc is not available")
+	}
+	dirs, err := toolpaths.New("testapp")
+	require.NoError(t, err)
+
+	m, found, err := dirs.FindUpContext(t.Context(), "/proc/self", []string{"etc"}, toolpaths.FindUpOptions{})
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "/", m.Dir)
+
+	opts := toolpaths.FindUpOptions{OneFilesystem: true}
+	_, found, err = dirs.FindUpContext(t.Context(), "/proc/self", []string{"etc"}, opts)
+	require.NoError(t, err)
+	assert.False(t, found, "/ is on a different device than /proc")
+}
+
+func TestFakeDirsFindUpContext(t *testing.T) {
+	base := testBase()
+	fake := toolpaths.NewFakeDirs(base)
+	fake.ExistingFiles = map[string]bool{
+		filepath.Join(base, ".git"):                 true,
+		filepath.Join(base, "home", "project", "x"): true,
+	}
+	start := filepath.Join(base, "home", "project")
+
+	m, found, err := fake.FindUpContext(t.Context(), start, []string{".git"}, toolpaths.FindUpOptions{})
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, base, m.Dir)
+
+	_, found, err = fake.FindUpContext(t.Context(), start, []string{".git"}, toolpaths.FindUpOptions{StopAtHome: true})
+	require.NoError(t, err)
+	assert.False(t, found, "HomeDirVal bounds the walk")
+}
@@ -0,0 +1,67 @@
+package toolpaths
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// ForUser creates a PlatformDirs that resolves directories for the named
+// OS user instead of the calling process, for installers and admin tools
+// that manage other accounts' files. The user's home directory and UID come
+// from os/user and replace Config.HomeDir and Config.UID.
+//
+// The caller's environment describes the caller, so unless Config.LookupEnv
+// is set the new PlatformDirs sees only HOME and, on Linux,
+// XDG_RUNTIME_DIR=/run/user/{uid}: user directories take their XDG or
+// native defaults under the user's home and the runtime directory is
+// /run/user/{uid}/{app}. Sandbox detection is disabled.
+//
+// Returns ErrAppNameRequired if Config.AppName is empty, or the os/user
+// error (such as user.UnknownUserError) if the user cannot be found.
+func ForUser(cfg Config, username string) (*PlatformDirs, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return nil, fmt.Errorf("toolpaths: look up user %q: %w", username, err)
+	}
+	return forUser(cfg, u)
+}
+
+// ForUID is like ForUser but looks the user up by numeric user ID. It is
+// not supported on Windows, where accounts are identified by SID.
+func ForUID(cfg Config, uid int) (*PlatformDirs, error) {
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return nil, fmt.Errorf("toolpaths: look up uid %d: %w", uid, err)
+	}
+	return forUser(cfg, u)
+}
+
+func forUser(cfg Config, u *user.User) (*PlatformDirs, error) {
+	cfg.HomeDir = u.HomeDir
+	cfg.IgnoreSandbox = true
+
+	// Windows SIDs are not numeric; keep the default UID there.
+	uid, uidErr := strconv.Atoi(u.Uid)
+	if uidErr == nil {
+		cfg.UID = func() int { return uid }
+	}
+
+	if cfg.LookupEnv == nil {
+		platform := cfg.Platform
+		if platform == PlatformAuto {
+			platform = detectPlatform()
+		}
+		env := map[string]string{"HOME": u.HomeDir}
+		if platform == PlatformLinux && uidErr == nil {
+			env["XDG_RUNTIME_DIR"] = filepath.Join("/run", "user", u.Uid)
+		}
+		cfg.LookupEnv = func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		}
+	}
+
+	return NewWithConfig(cfg)
+}
@@ -0,0 +1,69 @@
+package toolpaths_test
+
+import (
+	"os/user"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestForUser(t *testing.T) {
+	current, err := user.Current()
+	require.NoError(t, err)
+	t.Setenv("XDG_CONFIG_HOME", p(testBase(), "caller-config"))
+
+	dirs, err := toolpaths.ForUser(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformLinux}, current.Username)
+	require.NoError(t, err)
+
+	assert.Equal(t, p(current.HomeDir, ".config", "myapp"), dirs.UserConfigDir(), "caller's XDG_CONFIG_HOME is ignored")
+	assert.Equal(t, p(current.HomeDir, ".cache", "myapp"), dirs.UserCacheDir())
+	if runtime.GOOS != "windows" {
+		rt, err := dirs.UserRuntimeDir()
+		require.NoError(t, err)
+		assert.Equal(t, p("/run", "user", current.Uid, "myapp"), rt)
+	}
+
+	t.Run("app name required", func(t *testing.T) {
+		_, err := toolpaths.ForUser(toolpaths.Config{}, current.Username)
+		require.ErrorIs(t, err, toolpaths.ErrAppNameRequired)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := toolpaths.ForUser(toolpaths.Config{AppName: "myapp"}, "no-such-user-toolpaths")
+		var unknown user.UnknownUserError
+		require.ErrorAs(t, err, &unknown)
+	})
+}
+
+func TestForUID(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows accounts are identified by SID")
+	}
+	current, err := user.Current()
+	require.NoError(t, err)
+	uid, err := strconv.Atoi(current.Uid)
+	require.NoError(t, err)
+
+	dirs, err := toolpaths.ForUID(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformMacOS}, uid)
+	require.NoError(t, err)
+	assert.Equal(t, p(current.HomeDir, "Library", "Application Support", "myapp"), dirs.UserConfigDir())
+
+	t.Run("injected environment is kept", func(t *testing.T) {
+		dirs, err := toolpaths.ForUID(toolpaths.Config{
+			AppName:   "myapp",
+			Platform:  toolpaths.PlatformLinux,
+			LookupEnv: mapEnv(map[string]string{"XDG_DATA_HOME": p(testBase(), "data")}),
+			TempDir:   p(testBase(), "tmp"),
+		}, uid)
+		require.NoError(t, err)
+		assert.Equal(t, p(testBase(), "data", "myapp"), dirs.UserDataDir())
+		rt, err := dirs.UserRuntimeDir()
+		require.NoError(t, err)
+		assert.Equal(t, p(testBase(), "tmp", "myapp-"+current.Uid), rt)
+	})
+}
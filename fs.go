@@ -0,0 +1,152 @@
+package toolpaths
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing/fstest"
+)
+
+// FS is the filesystem used for existence checks, FindUp traversal, and
+// Ensure* directory creation. Names are native paths, as passed to the os
+// package, not the slash-separated relative names used by fs.FS.
+//
+// Set Config.FS or FakeDirs.FS to run against an in-memory tree such as
+// MapFS. The default is the real filesystem.
+type FS interface {
+	Stat(name string) (fs.FileInfo, error)
+	Open(name string) (fs.File, error)
+	MkdirAll(name string, perm fs.FileMode) error
+}
+
+// OSFS returns the FS backed by the real filesystem.
+func OSFS() FS {
+	return osFS{}
+}
+
+type osFS struct{}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+func (osFS) Open(name string) (fs.File, error)     { return os.Open(name) }
+
+func (osFS) MkdirAll(name string, perm fs.FileMode) error { return os.MkdirAll(name, perm) }
+
+// MapFS is an in-memory FS backed by an fstest.MapFS. Keys in Files are
+// slash-separated paths relative to the filesystem root, so the file
+// /home/me/.config/myapp/config.toml is stored under
+// "home/me/.config/myapp/config.toml" (and C:\Users\me\... under
+// "C:/Users/me/..." on Windows). Parent directories of files exist
+// implicitly; add entries with fs.ModeDir to give them explicit modes.
+//
+// Example:
+//
+//	mem := toolpaths.NewMapFS()
+//	_ = mem.WriteFile("/home/me/project/go.mod", []byte("module x\n"), 0o644)
+//	fake := toolpaths.NewFakeDirs("/home/me/.app")
+//	fake.FS = mem
+//	dir, _, ok := fake.FindUpFunc("/home/me/project/sub", []string{"go.mod"},
+//	    func(p string) bool { data, err := mem.ReadFile(p); return err == nil && len(data) > 0 })
+type MapFS struct {
+	Files fstest.MapFS
+}
+
+// Compile-time check that MapFS implements FS.
+var _ FS = (*MapFS)(nil)
+
+// NewMapFS creates an empty MapFS.
+func NewMapFS() *MapFS {
+	return &MapFS{Files: fstest.MapFS{}}
+}
+
+// Stat returns the file info for the named path.
+func (m *MapFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fs.Stat(m.Files, mapKey(name))
+	return info, pathError(err, name)
+}
+
+// Open opens the named path for reading.
+func (m *MapFS) Open(name string) (fs.File, error) {
+	f, err := m.Files.Open(mapKey(name))
+	return f, pathError(err, name)
+}
+
+// ReadFile returns the contents of the named file.
+func (m *MapFS) ReadFile(name string) ([]byte, error) {
+	data, err := m.Files.ReadFile(mapKey(name))
+	return data, pathError(err, name)
+}
+
+// MkdirAll creates the named directory and any missing parents with the
+// given permissions. Like os.MkdirAll, it fails if a path element exists
+// and is not a directory.
+func (m *MapFS) MkdirAll(name string, perm fs.FileMode) error {
+	if m.Files == nil {
+		m.Files = fstest.MapFS{}
+	}
+	key := mapKey(name)
+	if key == "." {
+		return nil
+	}
+	elems := strings.Split(key, "/")
+	for i := range elems {
+		dir := strings.Join(elems[:i+1], "/")
+		info, err := fs.Stat(m.Files, dir)
+		switch {
+		case err != nil:
+			m.Files[dir] = &fstest.MapFile{Mode: fs.ModeDir | perm.Perm()}
+		case !info.IsDir():
+			return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+		}
+	}
+	return nil
+}
+
+// WriteFile creates or replaces the named file, creating missing parent
+// directories with mode 0755.
+func (m *MapFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := m.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	m.Files[mapKey(name)] = &fstest.MapFile{Data: data, Mode: perm.Perm()}
+	return nil
+}
+
+// mapKey converts a native path to a MapFS key. Relative paths are made
+// absolute against the working directory, as the os package would.
+func mapKey(name string) string {
+	p := filepath.Clean(name)
+	if !filepath.IsAbs(p) {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+	}
+	key := strings.TrimLeft(filepath.ToSlash(p), "/")
+	if key == "" {
+		return "."
+	}
+	return key
+}
+
+// pathError reports errors against the native path rather than the key.
+func pathError(err error, name string) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return &fs.PathError{Op: pe.Op, Path: name, Err: pe.Err}
+	}
+	return err
+}
+
+// fsExists reports whether path exists in fsys.
+func fsExists(fsys FS, path string) bool {
+	_, err := fsys.Stat(path)
+	return err == nil
+}
+
+// fsDirExists reports whether path is an existing directory in fsys.
+func fsDirExists(fsys FS, path string) bool {
+	info, err := fsys.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,177 @@
+package toolpaths_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestMapFS(t *testing.T) {
+	base := testBase()
+	mem := toolpaths.NewMapFS()
+	file := p(base, "etc", "myapp", "config.toml")
+	require.NoError(t, mem.WriteFile(file, []byte("x = 1\n"), 0o600))
+
+	t.Run("files keep contents and mode", func(t *testing.T) {
+		info, err := mem.Stat(file)
+		require.NoError(t, err)
+		assert.False(t, info.IsDir())
+		assert.Equal(t, fs.FileMode(0o600), info.Mode().Perm())
+
+		data, err := mem.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, "x = 1\n", string(data))
+
+		f, err := mem.Open(file)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	})
+
+	t.Run("parents are directories", func(t *testing.T) {
+		info, err := mem.Stat(p(base, "etc"))
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+
+		info, err = mem.Stat(string(filepath.Separator))
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+	})
+
+	t.Run("missing paths report the native name", func(t *testing.T) {
+		missing := p(base, "nope")
+		_, err := mem.Stat(missing)
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		var pe *fs.PathError
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, missing, pe.Path)
+	})
+
+	t.Run("MkdirAll", func(t *testing.T) {
+		dir := p(base, "var", "lib", "myapp")
+		require.NoError(t, mem.MkdirAll(dir, 0o750))
+		require.NoError(t, mem.MkdirAll(dir, 0o700), "existing directories are fine")
+		info, err := mem.Stat(dir)
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, fs.FileMode(0o750), info.Mode().Perm())
+
+		err = mem.MkdirAll(p(file, "sub"), 0o700)
+		require.ErrorIs(t, err, syscall.ENOTDIR)
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var empty toolpaths.MapFS
+		require.NoError(t, empty.MkdirAll(p(base, "a"), 0o700))
+		_, err := empty.Stat(p(base, "a"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestFakeDirsFS(t *testing.T) {
+	base := testBase()
+	mem := toolpaths.NewMapFS()
+	fake := toolpaths.NewFakeDirs(base)
+	fake.FS = mem
+	fake.SetExisting(p(base, "config", "ignored.toml")) // FS takes precedence
+
+	require.NoError(t, mem.WriteFile(p(base, "system", "config", "config.toml"), nil, 0o644))
+
+	t.Run("find", func(t *testing.T) {
+		found, ok := fake.FindConfigFile("config.toml")
+		require.True(t, ok)
+		assert.Equal(t, p(base, "system", "config", "config.toml"), found)
+
+		_, ok = fake.FindConfigFile("ignored.toml")
+		assert.False(t, ok)
+	})
+
+	t.Run("ensure creates in memory", func(t *testing.T) {
+		fake.CreateDirs = true
+		dir, err := fake.EnsureUserStateDir()
+		require.NoError(t, err)
+		info, err := mem.Stat(dir)
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+		_, err = os.Stat(dir)
+		assert.True(t, os.IsNotExist(err), "nothing is written to disk")
+	})
+
+	t.Run("FindUpFunc predicate reads the same tree", func(t *testing.T) {
+		proj := p(base, "src", "proj")
+		require.NoError(t, mem.WriteFile(p(proj, "go.mod"), []byte("module proj\n"), 0o644))
+		require.NoError(t, mem.WriteFile(p(proj, "sub", "go.mod"), nil, 0o644))
+
+		nonEmpty := func(path string) bool {
+			data, err := mem.ReadFile(path)
+			return err == nil && len(data) > 0
+		}
+		dir, marker, ok := fake.FindUpFunc(p(proj, "sub", "pkg"), []string{"go.mod"}, nonEmpty)
+		require.True(t, ok)
+		assert.Equal(t, proj, dir)
+		assert.Equal(t, "go.mod", marker)
+	})
+
+	t.Run("cascade distinguishes files from directories", func(t *testing.T) {
+		proj := p(base, "work")
+		require.NoError(t, mem.MkdirAll(p(proj, ".git"), 0o755))
+		require.NoError(t, mem.MkdirAll(p(proj, ".myapp", "hooks"), 0o755))
+		require.NoError(t, mem.WriteFile(p(base, "config", "hooks"), nil, 0o644))
+
+		c, err := toolpaths.NewCascade("myapp",
+			toolpaths.WithCascadeDirs(fake),
+			toolpaths.WithGetwd(func() (string, error) { return proj, nil }),
+		)
+		require.NoError(t, err)
+
+		primary, alternates, err := c.ResolveDir("hooks")
+		require.NoError(t, err)
+		assert.Equal(t, p(proj, ".myapp", "hooks"), primary)
+		assert.Empty(t, alternates)
+	})
+}
+
+func TestPlatformDirsFS(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("XDG_CONFIG_DIRS", p(home, "etc"))
+
+	mem := toolpaths.NewMapFS()
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformLinux,
+		FS:       mem,
+	})
+	require.NoError(t, err)
+
+	systemFile := p(home, "etc", "myapp", "config.toml")
+	require.NoError(t, mem.WriteFile(systemFile, nil, 0o644))
+
+	found, ok := dirs.FindConfigFile("config.toml")
+	require.True(t, ok)
+	assert.Equal(t, systemFile, found)
+	assert.Equal(t, []string{systemFile}, dirs.ExistingManagedConfigFiles("config.toml"))
+
+	dir, err := dirs.EnsureUserConfigDir()
+	require.NoError(t, err)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err), "nothing is written to disk")
+
+	for _, info := range dirs.Diagnose().Dirs {
+		if info.Kind == "user-config" {
+			assert.True(t, info.Exists)
+			assert.Equal(t, fs.FileMode(0o700), info.Mode.Perm())
+		}
+	}
+
+	require.NoError(t, mem.WriteFile(p(home, "proj", ".git", "HEAD"), nil, 0o644))
+	root, marker, ok := dirs.FindUp(p(home, "proj", "a", "b"), ".git")
+	require.True(t, ok)
+	assert.Equal(t, p(home, "proj"), root)
+	assert.Equal(t, ".git", marker)
+}
@@ -0,0 +1,65 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// setNoHome simulates an environment without a home directory.
+func setNoHome(t *testing.T) {
+	t.Helper()
+	setTestHomeXDG(t)
+	toolpaths.SetHomeDirFunc(func() string { return "" })
+}
+
+func TestHomeDir(t *testing.T) {
+	home := setTestHomeXDG(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", RequireHomeDir: true})
+	require.NoError(t, err)
+	got, err := dirs.HomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, home, got)
+}
+
+func TestNoHomeDir(t *testing.T) {
+	setNoHome(t)
+
+	t.Run("required", func(t *testing.T) {
+		_, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", RequireHomeDir: true})
+		require.ErrorIs(t, err, toolpaths.ErrNoHomeDir)
+	})
+
+	t.Run("lenient by default", func(t *testing.T) {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformLinux})
+		require.NoError(t, err)
+		_, err = dirs.HomeDir()
+		require.ErrorIs(t, err, toolpaths.ErrNoHomeDir)
+		assert.Equal(t, p(".config", "myapp"), dirs.UserConfigDir())
+	})
+
+	t.Run("relative HOME", func(t *testing.T) {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", HomeDir: "relative"})
+		require.NoError(t, err)
+		_, err = dirs.HomeDir()
+		require.ErrorIs(t, err, toolpaths.ErrNoHomeDir)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		fallback := p(testBase(), "var", "lib", "myapp")
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:        "myapp",
+			Platform:       toolpaths.PlatformLinux,
+			HomeFallback:   fallback,
+			RequireHomeDir: true,
+		})
+		require.NoError(t, err)
+		got, err := dirs.HomeDir()
+		require.NoError(t, err)
+		assert.Equal(t, fallback, got)
+		assert.Equal(t, p(fallback, ".config", "myapp"), dirs.UserConfigDir())
+	})
+}
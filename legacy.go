@@ -0,0 +1,122 @@
+package toolpaths
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LegacyPaths declares the locations a release of the app that predates
+// XDG and native directories used, such as ~/.myapp or ~/.myapprc, so
+// existing users' files are still found and can be migrated.
+//
+// Entries may start with "~", which expands to the home directory;
+// relative entries are relative to the home directory too. Legacy
+// directories are listed last by the matching User*Dirs method, legacy
+// config and data directories are searched last by the Find*, Existing*,
+// and All*Paths methods, and Migrate moves their contents into the primary
+// directories. A directory that held everything, listed under several
+// kinds, is migrated as a whole into the first kind Migrate handles.
+type LegacyPaths struct {
+	UserConfig []string // e.g., "~/.myapp"
+	UserData   []string // e.g., "~/.myapp/data"
+	UserCache  []string
+	UserState  []string
+	UserLog    []string
+
+	// ConfigFiles maps a config file name to the single file an older
+	// release kept that config in, e.g., {"config.toml": "~/.myapprc"}.
+	// FindConfigFile("config.toml") then falls back to ~/.myapprc, and
+	// Migrate moves it to {UserConfigDir}/config.toml.
+	ConfigFiles map[string]string
+}
+
+// ---------------------------------------------------------------------
+// Internal: legacy resolution
+// ---------------------------------------------------------------------
+
+// legacyDirs returns the expanded legacy directories declared for dt.
+func (d *PlatformDirs) legacyDirs(dt dirType) []string {
+	lp := d.cfg.LegacyPaths
+	if lp == nil {
+		return nil
+	}
+
+	var entries []string
+	switch dt { //nolint:exhaustive // only user dir types have legacy locations
+	case userConfig:
+		entries = lp.UserConfig
+	case userData:
+		entries = lp.UserData
+	case userCache:
+		entries = lp.UserCache
+	case userState:
+		entries = lp.UserState
+	case userLog:
+		entries = lp.UserLog
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if dir := d.expandLegacy(entry); dir != "" && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// legacyConfigFile returns the expanded legacy file for a config file
+// name, or "" if none is declared.
+func (d *PlatformDirs) legacyConfigFile(filename string) string {
+	if d.cfg.LegacyPaths == nil {
+		return ""
+	}
+	return d.expandLegacy(d.cfg.LegacyPaths.ConfigFiles[filename])
+}
+
+// legacyConfigFiles returns every declared legacy config file keyed by the
+// config file name it becomes.
+func (d *PlatformDirs) legacyConfigFiles() map[string]string {
+	if d.cfg.LegacyPaths == nil {
+		return nil
+	}
+	files := make(map[string]string, len(d.cfg.LegacyPaths.ConfigFiles))
+	for name := range d.cfg.LegacyPaths.ConfigFiles {
+		if file := d.legacyConfigFile(name); file != "" {
+			files[name] = file
+		}
+	}
+	return files
+}
+
+// legacyPaths joins filename to each legacy directory of dt, then adds the
+// legacy config file for filename when dt is userConfig.
+func (d *PlatformDirs) legacyPaths(dt dirType, filename string) []string {
+	var paths []string
+	for _, dir := range d.legacyDirs(dt) {
+		paths = append(paths, filepath.Join(dir, filename))
+	}
+	if dt == userConfig {
+		if file := d.legacyConfigFile(filename); file != "" {
+			paths = append(paths, file)
+		}
+	}
+	return paths
+}
+
+// expandLegacy expands a leading "~" and makes relative entries relative
+// to the home directory.
+func (d *PlatformDirs) expandLegacy(entry string) string {
+	switch {
+	case entry == "":
+		return ""
+	case entry == "~":
+		return d.homeDir()
+	case strings.HasPrefix(entry, "~/") || strings.HasPrefix(entry, "~"+string(filepath.Separator)):
+		return filepath.Join(d.homeDir(), entry[2:])
+	case filepath.IsAbs(entry):
+		return filepath.Clean(entry)
+	default:
+		return filepath.Join(d.homeDir(), entry)
+	}
+}
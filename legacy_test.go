@@ -0,0 +1,136 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// newLegacyDirs returns dirs over a temp home that declare legacy config and
+// data locations.
+func newLegacyDirs(t *testing.T, platform toolpaths.Platform) (*toolpaths.PlatformDirs, string) {
+	t.Helper()
+	home := setTestHomeXDG(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: platform,
+		LegacyPaths: &toolpaths.LegacyPaths{
+			UserConfig:  []string{"~/.myapp", "~/.myapp", p(home, "old", "myapp")},
+			UserData:    []string{".myapp/data"},
+			ConfigFiles: map[string]string{"config.toml": "~/.myapprc"},
+		},
+	})
+	require.NoError(t, err)
+	return dirs, home
+}
+
+func TestLegacyPathsDirs(t *testing.T) {
+	t.Run("linux", func(t *testing.T) {
+		dirs, home := newLegacyDirs(t, toolpaths.PlatformLinux)
+		assert.Equal(t, []string{
+			p(home, ".config", "myapp"),
+			p(home, ".myapp"),
+			p(home, "old", "myapp"),
+		}, dirs.UserConfigDirs(), "legacy directories come last, expanded and de-duplicated")
+		assert.Equal(t, []string{p(home, ".local", "share", "myapp"), p(home, ".myapp", "data")}, dirs.UserDataDirs())
+		assert.Equal(t, []string{p(home, ".cache", "myapp")}, dirs.UserCacheDirs())
+		assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir(), "never the primary directory")
+	})
+
+	t.Run("macos", func(t *testing.T) {
+		dirs, home := newLegacyDirs(t, toolpaths.PlatformMacOS)
+		assert.Equal(t, []string{
+			p(home, "Library", "Application Support", "myapp"),
+			p(home, ".config", "myapp"),
+			p(home, ".myapp"),
+			p(home, "old", "myapp"),
+		}, dirs.UserConfigDirs())
+	})
+}
+
+func TestLegacyPathsFindConfigFile(t *testing.T) {
+	dirs, home := newLegacyDirs(t, toolpaths.PlatformLinux)
+
+	paths := dirs.AllConfigPaths("config.toml")
+	require.GreaterOrEqual(t, len(paths), 4)
+	assert.Equal(t, p(home, ".config", "myapp", "config.toml"), paths[0])
+	assert.Equal(t, []string{
+		p(home, ".myapp", "config.toml"),
+		p(home, "old", "myapp", "config.toml"),
+		p(home, ".myapprc"),
+	}, paths[len(paths)-3:], "legacy locations are searched after the system directories")
+	assert.NotContains(t, dirs.AllConfigPaths("other.toml"), p(home, ".myapprc"))
+	dataPaths := dirs.AllDataPaths("db.sqlite")
+	assert.Equal(t, p(home, ".myapp", "data", "db.sqlite"), dataPaths[len(dataPaths)-1])
+
+	_, ok := dirs.FindConfigFile("config.toml")
+	assert.False(t, ok)
+
+	writeFile(t, p(home, ".myapprc"), "legacy\n")
+	got, ok := dirs.FindConfigFile("config.toml")
+	require.True(t, ok)
+	assert.Equal(t, p(home, ".myapprc"), got)
+
+	writeFile(t, p(home, ".config", "myapp", "config.toml"), "current\n")
+	got, ok = dirs.FindConfigFile("config.toml")
+	require.True(t, ok)
+	assert.Equal(t, p(home, ".config", "myapp", "config.toml"), got)
+}
+
+func TestLegacyPathsExplain(t *testing.T) {
+	dirs, home := newLegacyDirs(t, toolpaths.PlatformLinux)
+
+	x, err := dirs.Explain("user-config")
+	require.NoError(t, err)
+	assert.Equal(t, p(home, ".config", "myapp"), x.Path)
+	var legacy []string
+	for _, step := range x.Steps {
+		if step.Strategy == toolpaths.StrategyLegacy {
+			assert.False(t, step.Chosen)
+			legacy = append(legacy, step.Path)
+		}
+	}
+	assert.Equal(t, []string{p(home, ".myapp"), p(home, "old", "myapp")}, legacy)
+	assert.Equal(t, dirs.UserConfigDirs(), x.Paths)
+}
+
+func TestLegacyPathsMigrate(t *testing.T) {
+	dirs, home := newLegacyDirs(t, toolpaths.PlatformLinux)
+	writeFile(t, p(home, ".myapp", "plugins", "a.lua"), "plugin\n")
+	writeFile(t, p(home, ".myapprc"), "legacy\n")
+	primary := p(home, ".config", "myapp")
+
+	report, err := dirs.Migrate(toolpaths.MigrateOptions{Kinds: []string{"user-config"}})
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 2)
+	assert.Equal(t, p(home, ".myapp"), report.Actions[0].From)
+	assert.Equal(t, p(home, ".myapprc"), report.Actions[1].From)
+	assert.Equal(t, p(primary, "config.toml"), report.Actions[1].To)
+
+	assert.Equal(t, "plugin\n", readFile(t, p(primary, "plugins", "a.lua")))
+	assert.Equal(t, "legacy\n", readFile(t, p(primary, "config.toml")))
+	assert.NoDirExists(t, p(home, ".myapp"))
+	assert.NoFileExists(t, p(home, ".myapprc"))
+	assert.FileExists(t, p(primary, toolpaths.MigrationMarker+"-user-config"))
+}
+
+func TestLegacyPathsMigrateDryRun(t *testing.T) {
+	dirs, home := newLegacyDirs(t, toolpaths.PlatformLinux)
+	writeFile(t, p(home, ".myapp", "a.toml"), "a\n")
+	writeFile(t, p(home, "old", "myapp", "b.toml"), "b\n")
+	primary := p(home, ".config", "myapp")
+
+	report, err := dirs.Migrate(toolpaths.MigrateOptions{DryRun: true, Kinds: []string{"user-config"}})
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 2)
+	assert.Equal(t, toolpaths.MigrationAction{
+		Kind: "user-config", From: p(home, ".myapp"), To: primary, Op: "move", Reason: "primary directory does not exist",
+	}, report.Actions[0])
+	assert.Equal(t, toolpaths.MigrationAction{
+		Kind: "user-config", From: p(home, "old", "myapp", "b.toml"), To: p(primary, "b.toml"), Op: "move",
+	}, report.Actions[1], "the second fallback merges into the directory the first would create")
+	assert.NoDirExists(t, primary)
+}
@@ -0,0 +1,107 @@
+package toolpaths
+
+import "path/filepath"
+
+// ---------------------------------------------------------------------
+// Managed policy directories
+// ---------------------------------------------------------------------
+
+// ManagedRequiredConfigDir returns the directory for administrator-enforced
+// configuration. Settings found here take precedence over user config.
+//
+//   - Linux/BSD: /etc/{app}/managed/required
+//   - macOS:     /Library/Managed Preferences/{app}/Required
+//   - Windows:   %ProgramData%\{author}\{app}\Policies\Required
+//
+// No environment variable relocates this directory.
+func (d *PlatformDirs) ManagedRequiredConfigDir() string {
+	return d.resolveManagedDir(managedRequired)
+}
+
+// ManagedRequiredConfigPath returns a path within the managed-required directory.
+func (d *PlatformDirs) ManagedRequiredConfigPath(elem ...string) string {
+	return path(d.ManagedRequiredConfigDir(), elem...)
+}
+
+// ManagedRecommendedConfigDir returns the directory for administrator-provided
+// defaults. Settings found here have the lowest precedence; user and system
+// config override them.
+//
+//   - Linux/BSD: /etc/{app}/managed/recommended
+//   - macOS:     /Library/Managed Preferences/{app}/Recommended
+//   - Windows:   %ProgramData%\{author}\{app}\Policies\Recommended
+func (d *PlatformDirs) ManagedRecommendedConfigDir() string {
+	if dir := d.fromEnvOverride(managedRecommended); dir != "" {
+		return dir
+	}
+	return d.resolveManagedDir(managedRecommended)
+}
+
+// ManagedRecommendedConfigPath returns a path within the managed-recommended directory.
+func (d *PlatformDirs) ManagedRecommendedConfigPath(elem ...string) string {
+	return path(d.ManagedRecommendedConfigDir(), elem...)
+}
+
+// FindManagedConfigFile finds a file across the managed policy layers and
+// the regular config directories, returning the first existing path in
+// the order of AllManagedConfigPaths.
+func (d *PlatformDirs) FindManagedConfigFile(filename string) (string, bool) {
+	for _, p := range d.AllManagedConfigPaths(filename) {
+		if d.fileExists(p) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// AllManagedConfigPaths returns all possible paths for a config file with
+// the managed policy layers wrapped around AllConfigPaths, in priority order:
+// managed-required, user config, system configs, managed-recommended.
+// To merge settings, apply the paths in reverse order so that required
+// policies are applied last. Does not check if files exist.
+func (d *PlatformDirs) AllManagedConfigPaths(filename string) []string {
+	paths := []string{d.ManagedRequiredConfigPath(filename)}
+	paths = append(paths, d.AllConfigPaths(filename)...)
+	return append(paths, d.ManagedRecommendedConfigPath(filename))
+}
+
+// ExistingManagedConfigFiles returns paths to all existing instances of a
+// config file across the managed policy layers and the regular config
+// directories, in priority order.
+func (d *PlatformDirs) ExistingManagedConfigFiles(filename string) []string {
+	var existing []string
+	for _, p := range d.AllManagedConfigPaths(filename) {
+		if d.fileExists(p) {
+			existing = append(existing, p)
+		}
+	}
+	return existing
+}
+
+// ---------------------------------------------------------------------
+// Internal: managed directory resolution
+// ---------------------------------------------------------------------
+
+// resolveManagedDir returns the platform-native managed policy directory.
+// Managed locations are deployed by administrators, so XDG environment
+// variables and XDGOnAllPlatforms do not affect them.
+func (d *PlatformDirs) resolveManagedDir(dt dirType) string {
+	var level, nativeLevel string
+	switch dt { //nolint:exhaustive // only managed dir types
+	case managedRequired:
+		level, nativeLevel = "required", "Required"
+	case managedRecommended:
+		level, nativeLevel = "recommended", "Recommended"
+	default:
+		return ""
+	}
+
+	switch d.platform { //nolint:exhaustive // XDG platforms use the /etc layout
+	case PlatformMacOS:
+		return filepath.Join("/Library", "Managed Preferences", d.appPath(dt), nativeLevel)
+	case PlatformWindows:
+		return filepath.Join(d.windowsProgramData(), d.windowsAppPath(dt), "Policies", nativeLevel)
+	default:
+		return filepath.Join("/etc", d.appPath(dt), "managed", level)
+	}
+}
@@ -0,0 +1,231 @@
+package toolpaths_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestManagedConfigDirs(t *testing.T) {
+	tests := []struct {
+		platform    toolpaths.Platform
+		required    string
+		recommended string
+	}{
+		{
+			toolpaths.PlatformLinux,
+			filepath.Join("/etc", "testapp", "managed", "required"),
+			filepath.Join("/etc", "testapp", "managed", "recommended"),
+		},
+		{
+			toolpaths.PlatformFreeBSD,
+			filepath.Join("/etc", "testapp", "managed", "required"),
+			filepath.Join("/etc", "testapp", "managed", "recommended"),
+		},
+		{
+			toolpaths.PlatformMacOS,
+			filepath.Join("/Library", "Managed Preferences", "testapp", "Required"),
+			filepath.Join("/Library", "Managed Preferences", "testapp", "Recommended"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform.String(), func(t *testing.T) {
+			dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+				AppName:  "testapp",
+				Platform: tt.platform,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.required, dirs.ManagedRequiredConfigDir())
+			assert.Equal(t, tt.recommended, dirs.ManagedRecommendedConfigDir())
+			assert.Equal(t, filepath.Join(tt.required, "policy.yaml"), dirs.ManagedRequiredConfigPath("policy.yaml"))
+		})
+	}
+}
+
+func TestManagedConfigDirsWindows(t *testing.T) {
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "testapp",
+		AppAuthor: "MyCompany",
+		Platform:  toolpaths.PlatformWindows,
+	})
+	require.NoError(t, err)
+
+	// On actual Windows, path includes the ProgramData drive letter.
+	suffix := filepath.Join("ProgramData", "MyCompany", "testapp", "Policies", "Required")
+	assert.True(t, strings.HasSuffix(dirs.ManagedRequiredConfigDir(), suffix),
+		"expected path to end with %q, got %q", suffix, dirs.ManagedRequiredConfigDir())
+
+	suffix = filepath.Join("ProgramData", "MyCompany", "testapp", "Policies", "Recommended")
+	assert.True(t, strings.HasSuffix(dirs.ManagedRecommendedConfigDir(), suffix),
+		"expected path to end with %q, got %q", suffix, dirs.ManagedRecommendedConfigDir())
+}
+
+func TestManagedConfigDirsEnvOverride(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("TESTAPP_MANAGED_RECOMMENDED", filepath.Join(base, "rec"))
+	t.Setenv("TESTAPP_MANAGED_REQUIRED", filepath.Join(base, "req"))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "testapp",
+		Platform:  toolpaths.PlatformLinux,
+		EnvPrefix: "TESTAPP",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(base, "rec"), dirs.ManagedRecommendedConfigDir())
+	assert.Equal(t, filepath.Join("/etc", "testapp", "managed", "required"), dirs.ManagedRequiredConfigDir(),
+		"required policy is never relocated by env")
+}
+
+func TestAllManagedConfigPaths(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(home, "etc"))
+	t.Setenv("TESTAPP_MANAGED_RECOMMENDED", filepath.Join(home, "managed", "recommended"))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "testapp",
+		Platform: toolpaths.PlatformLinux,
+		EnvOverrides: &toolpaths.EnvOverrides{
+			ManagedRecommended: "TESTAPP_MANAGED_RECOMMENDED",
+		},
+	})
+	require.NoError(t, err)
+
+	required := filepath.Join("/etc", "testapp", "managed", "required", "policy.yaml")
+	user := filepath.Join(home, ".config", "testapp", "policy.yaml")
+	system := filepath.Join(home, "etc", "testapp", "policy.yaml")
+	recommended := filepath.Join(home, "managed", "recommended", "policy.yaml")
+
+	assert.Equal(t, []string{required, user, system, recommended}, dirs.AllManagedConfigPaths("policy.yaml"))
+
+	_, found := dirs.FindManagedConfigFile("policy.yaml")
+	assert.False(t, found)
+
+	for _, f := range []string{user, recommended} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(f), 0o755))
+		require.NoError(t, os.WriteFile(f, nil, 0o644))
+	}
+
+	got, found := dirs.FindManagedConfigFile("policy.yaml")
+	require.True(t, found)
+	assert.Equal(t, user, got)
+	assert.Equal(t, []string{user, recommended}, dirs.ExistingManagedConfigFiles("policy.yaml"))
+}
+
+func TestFakeDirsManagedConfig(t *testing.T) {
+	base := testBase()
+	fake := toolpaths.NewFakeDirs(base)
+	fake.SetExisting(p(base, "managed", "required", "policy.yaml"))
+	fake.SetExisting(p(base, "config", "policy.yaml"))
+
+	assert.Equal(t, p(base, "managed", "required"), fake.ManagedRequiredConfigDir())
+	assert.Equal(t, p(base, "managed", "recommended", "x"), fake.ManagedRecommendedConfigPath("x"))
+
+	found, ok := fake.FindManagedConfigFile("policy.yaml")
+	require.True(t, ok)
+	assert.Equal(t, p(base, "managed", "required", "policy.yaml"), found)
+	assert.Len(t, fake.ExistingManagedConfigFiles("policy.yaml"), 2)
+
+	fake.ManagedRequiredDirVal = ""
+	fake.ManagedRecommendedDirVal = ""
+	assert.Equal(t, fake.AllConfigPaths("policy.yaml"), fake.AllManagedConfigPaths("policy.yaml"))
+}
+
+func TestCascadeManagedLayers(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+
+	t.Run("scope order", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, project, toolpaths.WithManagedLayers())
+
+		var names []string
+		for _, sc := range c.Scopes() {
+			names = append(names, sc.Name)
+		}
+		assert.Equal(t, []string{
+			toolpaths.ScopeManagedReq,
+			toolpaths.ScopeLocal,
+			toolpaths.ScopeProject,
+			toolpaths.ScopeUser,
+			toolpaths.ScopeSystem,
+			toolpaths.ScopeManagedRec,
+		}, names)
+	})
+
+	t.Run("required wins and recommended is last", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, toolpaths.WithManagedLayers())
+		fake.SetExisting(p(base, "managed", "required", "policy.yaml"))
+		fake.SetExisting(p(base, "config", "policy.yaml"))
+		fake.SetExisting(p(base, "managed", "recommended", "policy.yaml"))
+
+		primary, alternates, err := c.ResolveFile("policy.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "managed", "required", "policy.yaml"), primary)
+		assert.Equal(t, []string{
+			p(base, "config", "policy.yaml"),
+			p(base, "managed", "recommended", "policy.yaml"),
+		}, alternates)
+
+		primary, _, err = c.ResolveFileIn("policy.yaml", []string{toolpaths.ScopeManagedRec})
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "managed", "recommended", "policy.yaml"), primary)
+	})
+
+	t.Run("managed scopes are read-only", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, project, toolpaths.WithManagedLayers())
+
+		_, err := c.DefaultPath("policy.yaml", toolpaths.ScopeManagedReq)
+		require.ErrorIs(t, err, toolpaths.ErrScopeNotWritable)
+		_, err = c.DefaultPath("policy.yaml", toolpaths.ScopeManagedRec)
+		require.ErrorIs(t, err, toolpaths.ErrScopeNotWritable)
+	})
+
+	t.Run("wraps custom scopes", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, project,
+			toolpaths.WithScopes(toolpaths.ScopeConfig{Name: "only", Priority: 50}),
+			toolpaths.WithManagedLayers(),
+		)
+
+		var names []string
+		for _, sc := range c.Scopes() {
+			names = append(names, sc.Name)
+		}
+		assert.Equal(t, []string{toolpaths.ScopeManagedReq, "only", toolpaths.ScopeManagedRec}, names)
+	})
+
+	t.Run("skipped when dirs lack managed directories", func(t *testing.T) {
+		tmp := t.TempDir()
+		for _, dir := range []string{p(tmp, "managed", "required"), p(tmp, "config")} {
+			require.NoError(t, os.MkdirAll(dir, 0o700))
+			require.NoError(t, os.WriteFile(p(dir, "policy.yaml"), nil, 0o600))
+		}
+
+		// Embedding only the Dirs interface hides the ManagedDirs methods.
+		dirs := struct{ toolpaths.Dirs }{toolpaths.NewFakeDirs(tmp)}
+		c, err := toolpaths.NewCascade("myapp",
+			toolpaths.WithCascadeDirs(dirs),
+			toolpaths.WithGetwd(func() (string, error) { return tmp, nil }),
+			toolpaths.WithManagedLayers(),
+		)
+		require.NoError(t, err)
+
+		primary, alternates, err := c.ResolveFile("policy.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, p(tmp, "config", "policy.yaml"), primary)
+		assert.Empty(t, alternates)
+	})
+
+	t.Run("ManagedScopes", func(t *testing.T) {
+		scopes := toolpaths.ManagedScopes()
+		require.Len(t, scopes, len(toolpaths.DefaultScopes())+2)
+		assert.Equal(t, toolpaths.ScopeManagedReq, scopes[0].Name)
+		assert.Equal(t, toolpaths.ScopeManagedRec, scopes[len(scopes)-1].Name)
+	})
+}
@@ -0,0 +1,437 @@
+package toolpaths
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// MigrationMarker prefixes the file Migrate leaves in a primary directory
+// once a kind's fallbacks have been migrated, so later runs skip that kind:
+// {MigrationMarker}-{kind}, e.g. .toolpaths-migrated-user-config. Kinds
+// that share a primary directory, such as user-config and user-data on
+// macOS, each get their own marker.
+const MigrationMarker = ".toolpaths-migrated"
+
+// ErrMigrateFS is returned by Migrate when Config.FS is not the real
+// filesystem: moving, copying, and linking files needs more than FS offers.
+var ErrMigrateFS = errors.New("toolpaths: Migrate needs the real filesystem")
+
+// MigrateMode selects how Migrate transfers fallback data.
+type MigrateMode int
+
+const (
+	// MigrateMove renames files into the primary directory and removes the
+	// emptied fallback directories. This is the zero value.
+	MigrateMove MigrateMode = iota
+
+	// MigrateCopy copies files, leaving the fallback intact.
+	MigrateCopy
+
+	// MigrateSymlink links the primary directory (or, if it already
+	// exists, each file in it) to the fallback data.
+	MigrateSymlink
+)
+
+func (m MigrateMode) String() string {
+	switch m {
+	case MigrateMove:
+		return "move"
+	case MigrateCopy:
+		return "copy"
+	case MigrateSymlink:
+		return "symlink"
+	default:
+		return "unknown"
+	}
+}
+
+// ConflictPolicy decides what Migrate does when a file exists in both the
+// fallback and the primary directory.
+type ConflictPolicy int
+
+const (
+	// ConflictSkip keeps the primary file. This is the zero value.
+	ConflictSkip ConflictPolicy = iota
+
+	// ConflictOverwrite replaces the primary file with the fallback file.
+	ConflictOverwrite
+
+	// ConflictNewer keeps whichever file was modified more recently.
+	ConflictNewer
+)
+
+func (c ConflictPolicy) String() string {
+	switch c {
+	case ConflictSkip:
+		return "skip"
+	case ConflictOverwrite:
+		return "overwrite"
+	case ConflictNewer:
+		return "newer"
+	default:
+		return "unknown"
+	}
+}
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	Mode     MigrateMode
+	Conflict ConflictPolicy
+
+	// DryRun reports the actions Migrate would take without touching the
+	// filesystem.
+	DryRun bool
+
+	// Kinds limits migration to the named user directory kinds (e.g.,
+	// "user-config"). Default: user-config, user-data, user-cache,
+	// user-state, and user-log.
+	Kinds []string
+}
+
+// MigrationAction records one step Migrate took, or would take in a dry run.
+type MigrationAction struct {
+	Kind string // Directory kind, as in DirInfo.Kind
+	From string // Fallback path
+	To   string // Primary path
+
+	// Op is the mode name ("move", "copy", "symlink"), or "skip" when
+	// nothing was transferred; Reason says why.
+	Op     string
+	Reason string
+}
+
+// MigrationReport lists the actions of one Migrate call in order.
+type MigrationReport struct {
+	DryRun  bool
+	Actions []MigrationAction
+}
+
+// Migrate moves, copies, or links data found in the fallback directories
+// (the XDG defaults User*Dirs lists on macOS and Windows, and any
+// Config.LegacyPaths directories) into the primary directories, along with
+// the LegacyPaths config files. The other directories User*Dirs may list,
+// such as systemd's extra service directories, are left alone. A primary directory that does not exist yet is
+// created from its fallback as a whole; otherwise files are merged one by
+// one under opts.Conflict. Each migrated kind leaves a MigrationMarker file
+// in its primary directory and is skipped by later calls.
+//
+// Migrate works on the real filesystem only, and returns ErrMigrateFS when
+// Config.FS is set to anything else. It stops at the first error and
+// returns the actions taken so far.
+func (d *PlatformDirs) Migrate(opts MigrateOptions) (MigrationReport, error) {
+	report := MigrationReport{DryRun: opts.DryRun}
+	if _, ok := d.fsys.(osFS); !ok {
+		return report, ErrMigrateFS
+	}
+
+	kinds := []dirType{userConfig, userData, userCache, userState, userLog}
+	if len(opts.Kinds) > 0 {
+		selected := make([]dirType, 0, len(opts.Kinds))
+		for _, name := range opts.Kinds {
+			i := slices.IndexFunc(kinds, func(dt dirType) bool { return dt.String() == name })
+			if i < 0 {
+				return report, fmt.Errorf("%w: %q", ErrUnknownKind, name)
+			}
+			selected = append(selected, kinds[i])
+		}
+		kinds = selected
+	}
+
+	m := &migration{opts: opts, report: &report, created: map[string]bool{}}
+	for _, dt := range kinds {
+		primary := d.platformUserDirs(dt)[0]
+		sources := d.migrationSources(dt, primary)
+		var files map[string]string
+		if dt == userConfig {
+			files = d.legacyConfigFiles()
+		}
+		if len(sources) == 0 && len(files) == 0 {
+			continue
+		}
+		if err := m.migrateKind(dt.String(), primary, sources, files); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// migrationSources returns the directories Migrate empties into primary
+// for dt: the XDG default that User*Dirs lists as a fallback on macOS and
+// Windows, then the legacy directories. The other directories User*Dirs
+// lists are locations in their own right, not fallbacks: systemd's extra
+// service directories are configured separately, and a portable tree
+// replaces the platform directories entirely.
+func (d *PlatformDirs) migrationSources(dt dirType, primary string) []string {
+	var sources []string
+	if dirs := d.platformUserDirs(dt); len(dirs) == 2 && dirs[1] == d.xdgUserDirDefault(dt) {
+		sources = append(sources, dirs[1])
+	}
+	for _, dir := range d.legacyDirs(dt) {
+		if dir != primary && !slices.Contains(sources, dir) {
+			sources = append(sources, dir)
+		}
+	}
+	return sources
+}
+
+// ---------------------------------------------------------------------
+// Internal: migration
+// ---------------------------------------------------------------------
+
+type migration struct {
+	opts   MigrateOptions
+	report *MigrationReport
+
+	// created holds the primary directories a dry run would have created,
+	// so later fallbacks merge into them as a real run would.
+	created map[string]bool
+}
+
+func (m *migration) record(kind, from, to, op, reason string) {
+	m.report.Actions = append(m.report.Actions, MigrationAction{Kind: kind, From: from, To: to, Op: op, Reason: reason})
+}
+
+// migrateKind migrates the fallback directories, then the legacy files
+// (keyed by their name in the primary directory), into primary.
+func (m *migration) migrateKind(kind, primary string, fallbacks []string, files map[string]string) error {
+	marker := filepath.Join(primary, MigrationMarker+"-"+kind)
+	if _, err := os.Lstat(marker); err == nil {
+		m.record(kind, "", primary, "skip", "already migrated")
+		return nil
+	}
+
+	migrated := false
+	for _, fallback := range fallbacks {
+		if info, err := os.Stat(fallback); err != nil || !info.IsDir() {
+			continue
+		}
+		migrated = true
+		var err error
+		if _, statErr := os.Lstat(primary); errors.Is(statErr, fs.ErrNotExist) && !m.created[primary] {
+			err = m.transferDir(kind, fallback, primary)
+		} else {
+			err = m.mergeDir(kind, fallback, primary)
+		}
+		if err != nil {
+			return fmt.Errorf("toolpaths: migrate %s from %s: %w", kind, fallback, err)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		source := files[name]
+		if info, err := os.Lstat(source); err != nil || info.IsDir() {
+			continue
+		}
+		migrated = true
+		fallbacks = append(fallbacks, source)
+		if err := m.mergeFile(kind, source, filepath.Join(primary, name)); err != nil {
+			return fmt.Errorf("toolpaths: migrate %s from %s: %w", kind, source, err)
+		}
+	}
+
+	if !migrated || m.opts.DryRun {
+		return nil
+	}
+	return writeMigrationMarker(marker, fallbacks)
+}
+
+// transferDir handles a primary directory that does not exist yet.
+func (m *migration) transferDir(kind, fallback, primary string) error {
+	m.record(kind, fallback, primary, m.opts.Mode.String(), "primary directory does not exist")
+	if m.opts.DryRun {
+		m.created[primary] = true
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(primary), 0o700); err != nil {
+		return err
+	}
+
+	switch m.opts.Mode {
+	case MigrateSymlink:
+		return os.Symlink(fallback, primary)
+	case MigrateCopy:
+		return copyTree(fallback, primary)
+	case MigrateMove:
+		if err := os.Rename(fallback, primary); err == nil {
+			return nil
+		}
+		// Across filesystems rename fails; copy and remove instead.
+		if err := copyTree(fallback, primary); err != nil {
+			return err
+		}
+		return os.RemoveAll(fallback)
+	default:
+		return fmt.Errorf("unknown migrate mode %d", m.opts.Mode)
+	}
+}
+
+// mergeDir transfers fallback files into an existing primary directory.
+func (m *migration) mergeDir(kind, fallback, primary string) error {
+	err := filepath.WalkDir(fallback, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(fallback, path)
+		if err != nil {
+			return err
+		}
+		return m.mergeFile(kind, path, filepath.Join(primary, rel))
+	})
+	if err != nil || m.opts.DryRun || m.opts.Mode != MigrateMove {
+		return err
+	}
+	removeEmptyDirs(fallback)
+	return nil
+}
+
+// mergeFile transfers one file unless the conflict policy keeps target.
+func (m *migration) mergeFile(kind, source, target string) error {
+	if skip, reason := m.keepExisting(source, target); skip {
+		m.record(kind, source, target, "skip", reason)
+		return nil
+	}
+	m.record(kind, source, target, m.opts.Mode.String(), "")
+	if m.opts.DryRun {
+		return nil
+	}
+	return m.transferFile(source, target)
+}
+
+// keepExisting applies the conflict policy when target already exists.
+func (m *migration) keepExisting(source, target string) (bool, string) {
+	targetInfo, err := os.Lstat(target)
+	if err != nil {
+		return false, ""
+	}
+	switch m.opts.Conflict {
+	case ConflictOverwrite:
+		return false, ""
+	case ConflictNewer:
+		sourceInfo, err := os.Lstat(source)
+		if err == nil && sourceInfo.ModTime().After(targetInfo.ModTime()) {
+			return false, ""
+		}
+		return true, "primary file is newer or the same age"
+	case ConflictSkip:
+		return true, "primary file exists"
+	default:
+		return true, "primary file exists"
+	}
+}
+
+func (m *migration) transferFile(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	switch m.opts.Mode {
+	case MigrateSymlink:
+		return os.Symlink(source, target)
+	case MigrateCopy:
+		return copyEntry(source, target)
+	case MigrateMove:
+		if err := os.Rename(source, target); err == nil {
+			return nil
+		}
+		if err := copyEntry(source, target); err != nil {
+			return err
+		}
+		return os.Remove(source)
+	default:
+		return fmt.Errorf("unknown migrate mode %d", m.opts.Mode)
+	}
+}
+
+// copyTree copies the directory tree at source to target.
+func copyTree(source, target string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+		if entry.IsDir() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			return os.MkdirAll(dest, info.Mode().Perm())
+		}
+		return copyEntry(path, dest)
+	})
+}
+
+// copyEntry copies a regular file, keeping its mode and modification
+// time, or recreates a symlink.
+func copyEntry(source, target string) error {
+	info, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(source)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, time.Time{}, info.ModTime())
+}
+
+// removeEmptyDirs removes dir and its subdirectories if they are empty
+// after a move, deepest first. Directories that still hold skipped files
+// are kept.
+func removeEmptyDirs(dir string) {
+	var dirs []string
+	_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for _, path := range slices.Backward(dirs) {
+		_ = os.Remove(path)
+	}
+}
+
+func writeMigrationMarker(marker string, fallbacks []string) error {
+	if err := os.MkdirAll(filepath.Dir(marker), 0o700); err != nil {
+		return err
+	}
+	content := "# Written by toolpaths after migrating from:\n"
+	for _, fallback := range fallbacks {
+		content += fallback + "\n"
+	}
+	return os.WriteFile(marker, []byte(content), 0o600)
+}
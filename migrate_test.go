@@ -0,0 +1,216 @@
+package toolpaths_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// newMigrateDirs returns macOS dirs over a temp home with config data in
+// the XDG fallback location.
+func newMigrateDirs(t *testing.T) (*toolpaths.PlatformDirs, string, string) {
+	t.Helper()
+	home := setTestHomeXDG(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformMacOS})
+	require.NoError(t, err)
+
+	fallback := p(home, ".config", "myapp")
+	writeFile(t, p(fallback, "config.toml"), "old = true\n")
+	writeFile(t, p(fallback, "themes", "dark.toml"), "dark\n")
+	return dirs, dirs.UserConfigDir(), fallback
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestMigrateMoveWholeDirectory(t *testing.T) {
+	dirs, primary, fallback := newMigrateDirs(t)
+
+	report, err := dirs.Migrate(toolpaths.MigrateOptions{Kinds: []string{"user-config"}})
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 1)
+	assert.Equal(t, toolpaths.MigrationAction{
+		Kind: "user-config", From: fallback, To: primary, Op: "move", Reason: "primary directory does not exist",
+	}, report.Actions[0])
+
+	assert.Equal(t, "old = true\n", readFile(t, p(primary, "config.toml")))
+	assert.Equal(t, "dark\n", readFile(t, p(primary, "themes", "dark.toml")))
+	assert.NoDirExists(t, fallback)
+	assert.FileExists(t, p(primary, toolpaths.MigrationMarker+"-user-config"))
+
+	t.Run("marker prevents a second run", func(t *testing.T) {
+		writeFile(t, p(fallback, "config.toml"), "recreated\n")
+		report, err := dirs.Migrate(toolpaths.MigrateOptions{Kinds: []string{"user-config"}})
+		require.NoError(t, err)
+		require.Len(t, report.Actions, 1)
+		assert.Equal(t, "skip", report.Actions[0].Op)
+		assert.Equal(t, "old = true\n", readFile(t, p(primary, "config.toml")))
+	})
+}
+
+func TestMigrateAllKinds(t *testing.T) {
+	home := setTestHomeXDG(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformMacOS})
+	require.NoError(t, err)
+	fallbacks := map[string]string{
+		"user-config": p(home, ".config", "myapp", "config.toml"),
+		"user-data":   p(home, ".local", "share", "myapp", "data.db"),
+		"user-cache":  p(home, ".cache", "myapp", "index"),
+		"user-state":  p(home, ".local", "state", "myapp", "history"),
+	}
+	for _, path := range fallbacks {
+		writeFile(t, path, filepath.Base(path))
+	}
+
+	_, err = dirs.Migrate(toolpaths.MigrateOptions{})
+	require.NoError(t, err)
+
+	// user-config, user-data, and user-state share Application Support, so
+	// a marker per directory would skip all but the first.
+	primaries := map[string]string{
+		"user-config": dirs.UserConfigDir(),
+		"user-data":   dirs.UserDataDir(),
+		"user-cache":  dirs.UserCacheDir(),
+		"user-state":  dirs.UserStateDir(),
+	}
+	for kind, path := range fallbacks {
+		name := filepath.Base(path)
+		assert.Equal(t, name, readFile(t, p(primaries[kind], name)), kind)
+		assert.NoFileExists(t, path, kind)
+		assert.FileExists(t, p(primaries[kind], toolpaths.MigrationMarker+"-"+kind), kind)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	dirs, primary, fallback := newMigrateDirs(t)
+	writeFile(t, p(primary, "config.toml"), "new = true\n")
+
+	report, err := dirs.Migrate(toolpaths.MigrateOptions{DryRun: true, Mode: toolpaths.MigrateCopy})
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	require.Len(t, report.Actions, 2)
+	assert.Equal(t, "skip", report.Actions[0].Op)
+	assert.Equal(t, "primary file exists", report.Actions[0].Reason)
+	assert.Equal(t, "copy", report.Actions[1].Op)
+	assert.Equal(t, p(primary, "themes", "dark.toml"), report.Actions[1].To)
+
+	assert.NoFileExists(t, p(primary, "themes", "dark.toml"))
+	assert.NoFileExists(t, p(primary, toolpaths.MigrationMarker+"-user-config"))
+	assert.FileExists(t, p(fallback, "config.toml"))
+}
+
+func TestMigrateConflictPolicies(t *testing.T) {
+	t.Run("overwrite and copy", func(t *testing.T) {
+		dirs, primary, fallback := newMigrateDirs(t)
+		writeFile(t, p(primary, "config.toml"), "new = true\n")
+
+		opts := toolpaths.MigrateOptions{Mode: toolpaths.MigrateCopy, Conflict: toolpaths.ConflictOverwrite}
+		_, err := dirs.Migrate(opts)
+		require.NoError(t, err)
+		assert.Equal(t, "old = true\n", readFile(t, p(primary, "config.toml")))
+		assert.FileExists(t, p(fallback, "config.toml"), "copy keeps the fallback")
+	})
+
+	t.Run("newer", func(t *testing.T) {
+		dirs, primary, fallback := newMigrateDirs(t)
+		writeFile(t, p(primary, "config.toml"), "new = true\n")
+		writeFile(t, p(primary, "themes", "dark.toml"), "stale\n")
+		past := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(p(fallback, "config.toml"), past, past))
+		require.NoError(t, os.Chtimes(p(primary, "themes", "dark.toml"), past, past))
+
+		_, err := dirs.Migrate(toolpaths.MigrateOptions{Conflict: toolpaths.ConflictNewer})
+		require.NoError(t, err)
+		assert.Equal(t, "new = true\n", readFile(t, p(primary, "config.toml")))
+		assert.Equal(t, "dark\n", readFile(t, p(primary, "themes", "dark.toml")))
+		assert.FileExists(t, p(fallback, "config.toml"), "skipped files stay behind")
+		assert.NoDirExists(t, p(fallback, "themes"), "emptied directories are removed")
+	})
+}
+
+func TestMigrateSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on Windows")
+	}
+	dirs, primary, fallback := newMigrateDirs(t)
+
+	_, err := dirs.Migrate(toolpaths.MigrateOptions{Mode: toolpaths.MigrateSymlink})
+	require.NoError(t, err)
+	target, err := os.Readlink(primary)
+	require.NoError(t, err)
+	assert.Equal(t, fallback, target)
+	assert.Equal(t, "old = true\n", readFile(t, p(primary, "config.toml")))
+}
+
+func TestMigrateNeedsRealFS(t *testing.T) {
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformMacOS,
+		HomeDir:  p(testBase(), "home"),
+		FS:       toolpaths.NewMapFS(),
+	})
+	require.NoError(t, err)
+
+	_, err = dirs.Migrate(toolpaths.MigrateOptions{DryRun: true})
+	require.ErrorIs(t, err, toolpaths.ErrMigrateFS)
+}
+
+func TestMigrateNothingToDo(t *testing.T) {
+	setTestHomeXDG(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformLinux})
+	require.NoError(t, err)
+	report, err := dirs.Migrate(toolpaths.MigrateOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, report.Actions, "XDG platforms have no fallbacks")
+
+	_, err = dirs.Migrate(toolpaths.MigrateOptions{Kinds: []string{"system-config"}})
+	require.ErrorIs(t, err, toolpaths.ErrUnknownKind)
+}
+
+func TestMigrateLeavesSystemdDirectories(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("systemd variables use ':' separators, which clash with Windows drive letters")
+	}
+	home := setTestHomeXDG(t)
+	first, second := p(home, "svc", "a"), p(home, "svc", "b")
+	t.Setenv("STATE_DIRECTORY", first+":"+second)
+	writeFile(t, p(second, "sub", "f"), "kept\n")
+
+	for _, platform := range []toolpaths.Platform{toolpaths.PlatformLinux, toolpaths.PlatformMacOS} {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:  "myapp",
+			Platform: platform,
+			Systemd:  toolpaths.SystemdUser,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{first, second}, dirs.UserStateDirs())
+
+		report, err := dirs.Migrate(toolpaths.MigrateOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.Empty(t, report.Actions, "every systemd entry is a configured directory, not a fallback")
+	}
+}
+
+func TestMigrateEnumStrings(t *testing.T) {
+	assert.Equal(t, "symlink", toolpaths.MigrateSymlink.String())
+	assert.Equal(t, "unknown", toolpaths.MigrateMode(9).String())
+	assert.Equal(t, "newer", toolpaths.ConflictNewer.String())
+	assert.Equal(t, "unknown", toolpaths.ConflictPolicy(9).String())
+}
@@ -11,14 +11,18 @@ import (
 // 1. Testing Windows path resolution logic on any platform
 // 2. Explicit Platform: PlatformWindows usage on non-Windows systems
 
-func windowsRoamingAppData() string {
-	return filepath.Join(userHomeDir(), "AppData", "Roaming")
+func (d *PlatformDirs) windowsRoamingAppData() string {
+	return filepath.Join(d.homeDir(), "AppData", "Roaming")
 }
 
-func windowsLocalAppData() string {
-	return filepath.Join(userHomeDir(), "AppData", "Local")
+func (d *PlatformDirs) windowsLocalAppData() string {
+	return filepath.Join(d.homeDir(), "AppData", "Local")
 }
 
-func windowsProgramData() string {
+func (d *PlatformDirs) windowsProgramData() string {
 	return filepath.Join(string(filepath.Separator), "ProgramData")
 }
+
+func (d *PlatformDirs) windowsMediaDir(md mediaDir) string {
+	return d.windowsMediaDirDefault(md)
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package toolpaths
+
+import "io/fs"
+
+// fileOwner is not supported on platforms without Unix ownership.
+func fileOwner(fs.FileInfo) (string, int) {
+	return "", -1
+}
+
+// fileDevice is not supported on platforms without Unix device numbers.
+func fileDevice(fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,78 @@
+//go:build unix
+
+package toolpaths
+
+import (
+	"fmt"
+	"io/fs"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileOwner returns the owner of a file as "name (uid)", or just the uid
+// if the name cannot be looked up, along with the numeric uid.
+func fileOwner(fi fs.FileInfo) (string, int) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", -1
+	}
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username + " (" + uid + ")", int(st.Uid)
+	}
+	return uid, int(st.Uid)
+}
+
+// fileDevice returns the device number of the filesystem holding a file.
+func fileDevice(fi fs.FileInfo) (uint64, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true //nolint:gosec,unconvert // Dev is signed on some platforms
+}
+
+// chownRootDirs opens each element of elems in turn, starting at base,
+// without following symlinks, and gives every root-owned directory on the
+// way to uid and gid. A symlink fails with ErrChownSymlink, and owners are
+// changed through the open descriptor, so a directory cannot be swapped
+// for a symlink between the check and the change.
+func (osFS) chownRootDirs(base string, elems []string, uid, gid int) error {
+	fd, err := unix.Open(base, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &fs.PathError{Op: "open", Path: base, Err: err}
+	}
+	defer func() { _ = unix.Close(fd) }()
+
+	path := base
+	for _, elem := range elems {
+		path = filepath.Join(path, elem)
+		next, err := unix.Openat(fd, elem, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err != nil {
+			// The errno for a symlink under O_NOFOLLOW differs by OS.
+			var st unix.Stat_t
+			if unix.Fstatat(fd, elem, &st, unix.AT_SYMLINK_NOFOLLOW) == nil && st.Mode&unix.S_IFMT == unix.S_IFLNK {
+				return fmt.Errorf("%w: %q", ErrChownSymlink, path)
+			}
+			return &fs.PathError{Op: "open", Path: path, Err: err}
+		}
+		_ = unix.Close(fd)
+		fd = next
+
+		var st unix.Stat_t
+		if err := unix.Fstat(fd, &st); err != nil {
+			return &fs.PathError{Op: "stat", Path: path, Err: err}
+		}
+		if st.Uid != 0 {
+			continue // already someone's, such as the invoker's ~/.config
+		}
+		if err := unix.Fchown(fd, uid, gid); err != nil {
+			return &fs.PathError{Op: "chown", Path: path, Err: err}
+		}
+	}
+	return nil
+}
@@ -4,14 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 )
 
 // ErrAppNameRequired is returned when Config.AppName is empty or whitespace-only.
 var ErrAppNameRequired = errors.New("toolpaths: AppName is required")
 
+// ErrNoHomeDir is returned when the user's home directory cannot be
+// determined and Config.RequireHomeDir is set, and by PlatformDirs.HomeDir.
+var ErrNoHomeDir = errors.New("toolpaths: home directory unavailable")
+
 // OS name constants for runtime.GOOS comparisons.
 const osWindows = "windows"
 
@@ -19,6 +25,11 @@ const osWindows = "windows"
 type PlatformDirs struct {
 	cfg      Config
 	platform Platform
+	fsys     FS
+	sandbox  Sandbox
+	portable string
+	invoker  Invoker
+	profile  string
 }
 
 // New creates a PlatformDirs instance with default configuration.
@@ -37,7 +48,51 @@ func NewWithConfig(cfg Config) (*PlatformDirs, error) {
 	if platform == PlatformAuto {
 		platform = detectPlatform()
 	}
-	return &PlatformDirs{cfg: cfg, platform: platform}, nil
+	if cfg.EnvPrefix != "" {
+		prefix := strings.TrimSuffix(cfg.EnvPrefix, "_")
+		cfg.EnvOverrides = cfg.EnvOverrides.withDefaults(EnvOverridesForPrefix(prefix))
+		if cfg.HomeEnv == "" {
+			cfg.HomeEnv = prefix + "_HOME"
+		}
+		if cfg.ProfileEnv == "" {
+			cfg.ProfileEnv = prefix + "_PROFILE"
+		}
+	}
+	for _, kind := range cfg.Unversioned {
+		if !slices.Contains(Kinds(), kind) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+		}
+	}
+	// Only user directories are profiled, so only they can be unprofiled.
+	userKinds := Kinds()[:userRuntime+1]
+	for _, kind := range cfg.Unprofiled {
+		if !slices.Contains(userKinds, kind) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+		}
+	}
+	fsys := cfg.FS
+	if fsys == nil {
+		fsys = OSFS()
+	}
+	d := &PlatformDirs{
+		cfg:      cfg,
+		platform: platform,
+		fsys:     fsys,
+	}
+	if d.invoker = d.detectInvoker(); d.invoker.Name != "" {
+		d.applyInvoker()
+	}
+	if _, err := d.HomeDir(); err != nil && cfg.RequireHomeDir {
+		return nil, err
+	}
+	d.sandbox = d.detectSandbox()
+	d.portable = d.detectPortable()
+	profile, err := d.detectProfile()
+	if err != nil {
+		return nil, err
+	}
+	d.profile = profile
+	return d, nil
 }
 
 func detectPlatform() Platform {
@@ -71,7 +126,8 @@ func IsHomeDirFuncOverridden() bool {
 
 // SetHomeDirFunc sets a custom function to resolve the user's home directory.
 // This is intended for testing. Pass nil to restore the default behavior.
-// The function is not safe for concurrent use with other calls to this package.
+// The function is not safe for concurrent use with other calls to this package;
+// set Config.HomeDir and Config.LookupEnv instead to isolate one PlatformDirs.
 func SetHomeDirFunc(f func() string) {
 	if f == nil {
 		userHomeDirFunc = defaultUserHomeDir
@@ -91,16 +147,116 @@ func userHomeDir() string {
 // defaultUserHomeDir returns the current user's home directory.
 // On Unix systems, this checks $HOME first for testability, then falls back
 // to os.UserHomeDir(). On Windows, it uses os.UserHomeDir() directly.
+// If both fail, as under cron or in minimal containers, the home directory
+// from the user database (os/user) is used. Returns "" if none is known.
 func defaultUserHomeDir() string {
 	if runtime.GOOS != osWindows {
 		if home := os.Getenv("HOME"); home != "" {
 			return home
 		}
 	}
-	home, _ := os.UserHomeDir()
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return home
+	}
+	return userDatabaseHomeDir()
+}
+
+// userDatabaseHomeDir returns the current user's home directory from the
+// user database (os/user), without consulting the environment. Returns ""
+// if it is unknown.
+func userDatabaseHomeDir() string {
+	if u, err := user.Current(); err == nil {
+		return u.HomeDir
+	}
+	return ""
+}
+
+// ---------------------------------------------------------------------
+// Internal: per-instance environment
+// ---------------------------------------------------------------------
+
+// lookupEnv reads an environment variable through Config.LookupEnv, or
+// the process environment if it is nil.
+func (d *PlatformDirs) lookupEnv(key string) (string, bool) {
+	if d.cfg.LookupEnv != nil {
+		return d.cfg.LookupEnv(key)
+	}
+	return os.LookupEnv(key)
+}
+
+// getenv is lookupEnv without the presence flag, like os.Getenv.
+func (d *PlatformDirs) getenv(key string) string {
+	value, _ := d.lookupEnv(key)
+	return value
+}
+
+// HomeDir returns the home directory user directories are built on:
+// Config.HomeDir, or else $HOME, os.UserHomeDir, or the user database, or
+// else Config.HomeFallback. With Config.LookupEnv set, $HOME is read
+// through it (outside Windows) and the user database follows directly, so
+// the process environment is never consulted. Returns ErrNoHomeDir if none
+// yields an absolute path; the User* methods then build relative paths,
+// which Config.RequireHomeDir turns into a construction error instead.
+func (d *PlatformDirs) HomeDir() (string, error) {
+	if home := d.homeDir(); filepath.IsAbs(home) {
+		return home, nil
+	}
+	return "", ErrNoHomeDir
+}
+
+// homeDir returns the home directory, or "" or a relative path if none is
+// known. See HomeDir.
+func (d *PlatformDirs) homeDir() string {
+	if d.cfg.HomeDir != "" {
+		return d.cfg.HomeDir
+	}
+	var home string
+	if d.cfg.LookupEnv == nil || homeDirFuncOverridden {
+		home = userHomeDir()
+	} else {
+		// An injected environment replaces the process one entirely, so
+		// the process $HOME and os.UserHomeDir are not consulted.
+		if runtime.GOOS != osWindows {
+			home = d.getenv("HOME")
+		}
+		if home == "" {
+			home = userDatabaseHomeDir()
+		}
+	}
+	if !filepath.IsAbs(home) && d.cfg.HomeFallback != "" {
+		return d.cfg.HomeFallback
+	}
 	return home
 }
 
+// homeOverridden reports whether the home directory was injected, in which
+// case Windows Known Folders are derived from it rather than queried.
+func (d *PlatformDirs) homeOverridden() bool {
+	return d.cfg.HomeDir != "" || homeDirFuncOverridden
+}
+
+// tempDir returns Config.TempDir, or else os.TempDir.
+func (d *PlatformDirs) tempDir() string {
+	if d.cfg.TempDir != "" {
+		return d.cfg.TempDir
+	}
+	return os.TempDir()
+}
+
+// uid returns the user ID from Config.UID, or else os.Getuid.
+func (d *PlatformDirs) uid() int {
+	if d.cfg.UID != nil {
+		return d.cfg.UID()
+	}
+	return os.Getuid()
+}
+
+// Platform returns the platform paths are resolved for. PlatformAuto is
+// replaced by the detected platform during construction.
+func (d *PlatformDirs) Platform() Platform {
+	return d.platform
+}
+
 // String returns a human-readable summary of all resolved directory paths.
 func (d *PlatformDirs) String() string {
 	var b strings.Builder
@@ -158,7 +314,7 @@ func path(base string, elem ...string) string {
 // UserConfigDir returns the user-specific configuration directory.
 // This is the primary location for writing config files.
 func (d *PlatformDirs) UserConfigDir() string {
-	if dir := d.fromEnvOverride(userConfig); dir != "" {
+	if dir := d.overrideDir(userConfig); dir != "" {
 		return dir
 	}
 	return d.resolveUserDir(userConfig)
@@ -179,7 +335,7 @@ func (d *PlatformDirs) UserConfigPath(elem ...string) string {
 
 // UserDataDir returns the user-specific data directory.
 func (d *PlatformDirs) UserDataDir() string {
-	if dir := d.fromEnvOverride(userData); dir != "" {
+	if dir := d.overrideDir(userData); dir != "" {
 		return dir
 	}
 	return d.resolveUserDir(userData)
@@ -197,7 +353,7 @@ func (d *PlatformDirs) UserDataPath(elem ...string) string {
 
 // UserCacheDir returns the user-specific cache directory.
 func (d *PlatformDirs) UserCacheDir() string {
-	if dir := d.fromEnvOverride(userCache); dir != "" {
+	if dir := d.overrideDir(userCache); dir != "" {
 		return dir
 	}
 	return d.resolveUserDir(userCache)
@@ -215,7 +371,7 @@ func (d *PlatformDirs) UserCachePath(elem ...string) string {
 
 // UserStateDir returns the user-specific state directory.
 func (d *PlatformDirs) UserStateDir() string {
-	if dir := d.fromEnvOverride(userState); dir != "" {
+	if dir := d.overrideDir(userState); dir != "" {
 		return dir
 	}
 	return d.resolveUserDir(userState)
@@ -233,7 +389,7 @@ func (d *PlatformDirs) UserStatePath(elem ...string) string {
 
 // UserLogDir returns the user-specific log directory.
 func (d *PlatformDirs) UserLogDir() string {
-	if dir := d.fromEnvOverride(userLog); dir != "" {
+	if dir := d.overrideDir(userLog); dir != "" {
 		return dir
 	}
 	return d.resolveUserDir(userLog)
@@ -253,7 +409,7 @@ func (d *PlatformDirs) UserLogPath(elem ...string) string {
 // Returns an error if the runtime directory cannot be determined
 // (e.g., XDG_RUNTIME_DIR not set on Linux with no fallback).
 func (d *PlatformDirs) UserRuntimeDir() (string, error) {
-	if dir := d.fromEnvOverride(userRuntime); dir != "" {
+	if dir := d.overrideDir(userRuntime); dir != "" {
 		return dir, nil
 	}
 	return d.resolveRuntimeDir()
@@ -278,7 +434,7 @@ func (d *PlatformDirs) SystemConfigDirs() []string {
 	if dir := d.fromEnvOverride(systemConfig); dir != "" {
 		return []string{dir}
 	}
-	return d.resolveSystemDirs(systemConfig)
+	return d.withSystemdDirs(systemConfig, d.resolveSystemDirs(systemConfig)...)
 }
 
 // SystemConfigDir returns the primary system configuration directory.
@@ -321,7 +477,7 @@ func (d *PlatformDirs) SystemDataPath(elem ...string) string {
 // SystemCacheDir returns the system-wide cache directory.
 // Unlike SystemConfigDirs/SystemDataDirs, this is a single location (not a search path).
 func (d *PlatformDirs) SystemCacheDir() string {
-	if dir := d.fromEnvOverride(systemCache); dir != "" {
+	if dir := d.overrideDir(systemCache); dir != "" {
 		return dir
 	}
 	return d.resolveSystemSingleDir(systemCache)
@@ -335,7 +491,7 @@ func (d *PlatformDirs) SystemCachePath(elem ...string) string {
 // SystemStateDir returns the system-wide state directory.
 // This is for persistent data that isn't user-facing (databases, etc.).
 func (d *PlatformDirs) SystemStateDir() string {
-	if dir := d.fromEnvOverride(systemState); dir != "" {
+	if dir := d.overrideDir(systemState); dir != "" {
 		return dir
 	}
 	return d.resolveSystemSingleDir(systemState)
@@ -348,7 +504,7 @@ func (d *PlatformDirs) SystemStatePath(elem ...string) string {
 
 // SystemLogDir returns the system-wide log directory.
 func (d *PlatformDirs) SystemLogDir() string {
-	if dir := d.fromEnvOverride(systemLog); dir != "" {
+	if dir := d.overrideDir(systemLog); dir != "" {
 		return dir
 	}
 	return d.resolveSystemSingleDir(systemLog)
@@ -363,7 +519,7 @@ func (d *PlatformDirs) SystemLogPath(elem ...string) string {
 // On Linux/BSD this is /run/{app}. On macOS and Windows, this returns
 // an empty string as there is no equivalent concept.
 func (d *PlatformDirs) SystemRuntimeDir() string {
-	if dir := d.fromEnvOverride(systemRuntime); dir != "" {
+	if dir := d.overrideDir(systemRuntime); dir != "" {
 		return dir
 	}
 	return d.resolveSystemSingleDir(systemRuntime)
@@ -387,7 +543,7 @@ func (d *PlatformDirs) SystemRuntimePath(elem ...string) string {
 // (user first, then system) and returns the first existing path.
 func (d *PlatformDirs) FindConfigFile(filename string) (string, bool) {
 	for _, p := range d.AllConfigPaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			return p, true
 		}
 	}
@@ -395,15 +551,17 @@ func (d *PlatformDirs) FindConfigFile(filename string) (string, bool) {
 }
 
 // AllConfigPaths returns all possible paths for a config file,
-// in priority order (user config first, then system configs).
-// Does not check if files exist.
+// in priority order (user config first, then system configs, then any
+// Config.LegacyPaths locations). Does not check if files exist.
 func (d *PlatformDirs) AllConfigPaths(filename string) []string {
 	var paths []string
-	paths = append(paths, d.UserConfigPath(filename))
+	for _, dir := range d.withSystemdDirs(userConfig, d.UserConfigDir(), d.fallbackVersionDir(userConfig)) {
+		paths = append(paths, filepath.Join(dir, filename))
+	}
 	for _, dir := range d.SystemConfigDirs() {
 		paths = append(paths, filepath.Join(dir, filename))
 	}
-	return paths
+	return append(paths, d.legacyPaths(userConfig, filename)...)
 }
 
 // ExistingConfigFiles returns paths to all existing instances of a
@@ -411,7 +569,7 @@ func (d *PlatformDirs) AllConfigPaths(filename string) []string {
 func (d *PlatformDirs) ExistingConfigFiles(filename string) []string {
 	var existing []string
 	for _, p := range d.AllConfigPaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			existing = append(existing, p)
 		}
 	}
@@ -422,7 +580,7 @@ func (d *PlatformDirs) ExistingConfigFiles(filename string) []string {
 // (user first, then system) and returns the first existing path.
 func (d *PlatformDirs) FindDataFile(filename string) (string, bool) {
 	for _, p := range d.AllDataPaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			return p, true
 		}
 	}
@@ -430,15 +588,17 @@ func (d *PlatformDirs) FindDataFile(filename string) (string, bool) {
 }
 
 // AllDataPaths returns all possible paths for a data file,
-// in priority order (user first, then system).
-// Does not check if files exist.
+// in priority order (user first, then system, then any Config.LegacyPaths
+// directories). Does not check if files exist.
 func (d *PlatformDirs) AllDataPaths(filename string) []string {
-	var paths []string
-	paths = append(paths, d.UserDataPath(filename))
+	paths := []string{d.UserDataPath(filename)}
+	if prev := d.fallbackVersionDir(userData); prev != "" {
+		paths = append(paths, filepath.Join(prev, filename))
+	}
 	for _, dir := range d.SystemDataDirs() {
 		paths = append(paths, filepath.Join(dir, filename))
 	}
-	return paths
+	return append(paths, d.legacyPaths(userData, filename)...)
 }
 
 // ExistingDataFiles returns paths to all existing instances of a
@@ -446,7 +606,7 @@ func (d *PlatformDirs) AllDataPaths(filename string) []string {
 func (d *PlatformDirs) ExistingDataFiles(filename string) []string {
 	var existing []string
 	for _, p := range d.AllDataPaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			existing = append(existing, p)
 		}
 	}
@@ -457,7 +617,7 @@ func (d *PlatformDirs) ExistingDataFiles(filename string) []string {
 // (user first, then system) and returns the first existing path.
 func (d *PlatformDirs) FindCacheFile(filename string) (string, bool) {
 	for _, p := range d.AllCachePaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			return p, true
 		}
 	}
@@ -468,10 +628,7 @@ func (d *PlatformDirs) FindCacheFile(filename string) (string, bool) {
 // in priority order (user first, then system).
 // Does not check if files exist.
 func (d *PlatformDirs) AllCachePaths(filename string) []string {
-	return []string{
-		d.UserCachePath(filename),
-		d.SystemCachePath(filename),
-	}
+	return d.joinSingleDirs(userCache, systemCache, d.UserCacheDir(), d.SystemCacheDir(), filename)
 }
 
 // ExistingCacheFiles returns paths to all existing instances of a
@@ -479,7 +636,7 @@ func (d *PlatformDirs) AllCachePaths(filename string) []string {
 func (d *PlatformDirs) ExistingCacheFiles(filename string) []string {
 	var existing []string
 	for _, p := range d.AllCachePaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			existing = append(existing, p)
 		}
 	}
@@ -490,7 +647,7 @@ func (d *PlatformDirs) ExistingCacheFiles(filename string) []string {
 // (user first, then system) and returns the first existing path.
 func (d *PlatformDirs) FindStateFile(filename string) (string, bool) {
 	for _, p := range d.AllStatePaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			return p, true
 		}
 	}
@@ -501,10 +658,7 @@ func (d *PlatformDirs) FindStateFile(filename string) (string, bool) {
 // in priority order (user first, then system).
 // Does not check if files exist.
 func (d *PlatformDirs) AllStatePaths(filename string) []string {
-	return []string{
-		d.UserStatePath(filename),
-		d.SystemStatePath(filename),
-	}
+	return d.joinSingleDirs(userState, systemState, d.UserStateDir(), d.SystemStateDir(), filename)
 }
 
 // ExistingStateFiles returns paths to all existing instances of a
@@ -512,7 +666,7 @@ func (d *PlatformDirs) AllStatePaths(filename string) []string {
 func (d *PlatformDirs) ExistingStateFiles(filename string) []string {
 	var existing []string
 	for _, p := range d.AllStatePaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			existing = append(existing, p)
 		}
 	}
@@ -523,7 +677,7 @@ func (d *PlatformDirs) ExistingStateFiles(filename string) []string {
 // (user first, then system) and returns the first existing path.
 func (d *PlatformDirs) FindLogFile(filename string) (string, bool) {
 	for _, p := range d.AllLogPaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			return p, true
 		}
 	}
@@ -534,10 +688,7 @@ func (d *PlatformDirs) FindLogFile(filename string) (string, bool) {
 // in priority order (user first, then system).
 // Does not check if files exist.
 func (d *PlatformDirs) AllLogPaths(filename string) []string {
-	return []string{
-		d.UserLogPath(filename),
-		d.SystemLogPath(filename),
-	}
+	return d.joinSingleDirs(userLog, systemLog, d.UserLogDir(), d.SystemLogDir(), filename)
 }
 
 // ExistingLogFiles returns paths to all existing instances of a
@@ -545,7 +696,7 @@ func (d *PlatformDirs) AllLogPaths(filename string) []string {
 func (d *PlatformDirs) ExistingLogFiles(filename string) []string {
 	var existing []string
 	for _, p := range d.AllLogPaths(filename) {
-		if fileExists(p) {
+		if d.fileExists(p) {
 			existing = append(existing, p)
 		}
 	}
@@ -557,7 +708,7 @@ func (d *PlatformDirs) ExistingLogFiles(filename string) []string {
 // Note: System runtime directories don't exist on macOS/Windows.
 func (d *PlatformDirs) FindRuntimeFile(filename string) (string, bool) {
 	for _, p := range d.AllRuntimePaths(filename) {
-		if p != "" && fileExists(p) {
+		if p != "" && d.fileExists(p) {
 			return p, true
 		}
 	}
@@ -569,19 +720,10 @@ func (d *PlatformDirs) FindRuntimeFile(filename string) (string, bool) {
 // Does not check if files exist. Empty strings are included for
 // platforms where certain runtime directories don't exist.
 func (d *PlatformDirs) AllRuntimePaths(filename string) []string {
-	var paths []string
-
-	// User runtime - may error, in which case skip it
-	if userRuntime, err := d.UserRuntimePath(filename); err == nil {
-		paths = append(paths, userRuntime)
-	}
-
-	// System runtime - may be empty on some platforms
-	if sysRuntime := d.SystemRuntimePath(filename); sysRuntime != "" {
-		paths = append(paths, sysRuntime)
-	}
-
-	return paths
+	// User runtime may error and system runtime may be empty on some
+	// platforms; joinSingleDirs skips both
+	userRuntimeDir, _ := d.UserRuntimeDir()
+	return d.joinSingleDirs(userRuntime, systemRuntime, userRuntimeDir, d.SystemRuntimeDir(), filename)
 }
 
 // ExistingRuntimeFiles returns paths to all existing instances of a
@@ -589,13 +731,27 @@ func (d *PlatformDirs) AllRuntimePaths(filename string) []string {
 func (d *PlatformDirs) ExistingRuntimeFiles(filename string) []string {
 	var existing []string
 	for _, p := range d.AllRuntimePaths(filename) {
-		if p != "" && fileExists(p) {
+		if p != "" && d.fileExists(p) {
 			existing = append(existing, p)
 		}
 	}
 	return existing
 }
 
+// joinSingleDirs joins filename onto the user and system directories of a
+// kind with a single directory per scope, including every systemd
+// directory when a service sets several.
+func (d *PlatformDirs) joinSingleDirs(user, system dirType, userDir, systemDir, filename string) []string {
+	var paths []string
+	for _, dir := range d.withSystemdDirs(user, userDir, d.fallbackVersionDir(user)) {
+		paths = append(paths, filepath.Join(dir, filename))
+	}
+	for _, dir := range d.withSystemdDirs(system, systemDir) {
+		paths = append(paths, filepath.Join(dir, filename))
+	}
+	return paths
+}
+
 // ---------------------------------------------------------------------
 // Ensure utilities (create directories if needed)
 // ---------------------------------------------------------------------
@@ -604,38 +760,48 @@ func (d *PlatformDirs) ExistingRuntimeFiles(filename string) []string {
 // exist and returns its path.
 func (d *PlatformDirs) EnsureUserConfigDir() (string, error) {
 	dir := d.UserConfigDir()
-	return dir, os.MkdirAll(dir, 0o700)
+	return dir, d.fsys.MkdirAll(dir, 0o700)
 }
 
 // EnsureUserDataDir creates the user data directory if needed.
 func (d *PlatformDirs) EnsureUserDataDir() (string, error) {
 	dir := d.UserDataDir()
-	return dir, os.MkdirAll(dir, 0o700)
+	return dir, d.fsys.MkdirAll(dir, 0o700)
 }
 
 // EnsureUserCacheDir creates the user cache directory if needed.
 func (d *PlatformDirs) EnsureUserCacheDir() (string, error) {
 	dir := d.UserCacheDir()
-	return dir, os.MkdirAll(dir, 0o700)
+	return dir, d.fsys.MkdirAll(dir, 0o700)
 }
 
 // EnsureUserStateDir creates the user state directory if needed.
 func (d *PlatformDirs) EnsureUserStateDir() (string, error) {
 	dir := d.UserStateDir()
-	return dir, os.MkdirAll(dir, 0o700)
+	return dir, d.fsys.MkdirAll(dir, 0o700)
 }
 
 // EnsureUserLogDir creates the user log directory if needed.
 func (d *PlatformDirs) EnsureUserLogDir() (string, error) {
 	dir := d.UserLogDir()
-	return dir, os.MkdirAll(dir, 0o700)
+	return dir, d.fsys.MkdirAll(dir, 0o700)
 }
 
 // ---------------------------------------------------------------------
 // Internal: env override helpers
 // ---------------------------------------------------------------------
 
+// fromEnvOverride returns the per-kind EnvOverrides value for dt, or else
+// the directory under the HomeEnv root.
 func (d *PlatformDirs) fromEnvOverride(dt dirType) string {
+	if dir := d.fromKindEnv(dt); dir != "" {
+		return dir
+	}
+	return d.homeEnvDir(dt)
+}
+
+// fromKindEnv returns the value of the EnvOverrides variable for dt.
+func (d *PlatformDirs) fromKindEnv(dt dirType) string {
 	if d.cfg.EnvOverrides == nil {
 		return ""
 	}
@@ -643,33 +809,96 @@ func (d *PlatformDirs) fromEnvOverride(dt dirType) string {
 	if envVar == "" {
 		return ""
 	}
-	val := os.Getenv(envVar)
+	val := d.getenv(envVar)
 	if val == "" {
 		return ""
 	}
 	if d.cfg.EnvOverrides.AppendAppName {
-		return filepath.Join(val, d.appPath())
+		return filepath.Join(val, d.appPath(dt))
+	}
+	if sub := d.profileSubdir(dt); sub != "" {
+		return filepath.Join(val, sub)
 	}
 	return val
 }
 
+// homeEnvDir returns the directory for dt under the HomeEnv root, or "" if
+// the variable is unset or does not cover dt.
+func (d *PlatformDirs) homeEnvDir(dt dirType) string {
+	if d.cfg.HomeEnv == "" {
+		return ""
+	}
+	root := d.getenv(d.cfg.HomeEnv)
+	if root == "" {
+		return ""
+	}
+	if dt > userRuntime && !d.cfg.HomeEnvIncludesSystem {
+		return ""
+	}
+	if sub := rootSubdir(dt); sub != "" {
+		return filepath.Join(root, sub, d.profileSubdir(dt))
+	}
+	return ""
+}
+
+// rootSubdir returns where dt lives in a single-root tree (HomeEnv or a
+// portable install): user directories at the top, system directories
+// under system/. Managed directories have no place in such a tree.
+func rootSubdir(dt dirType) string {
+	switch dt {
+	case userConfig:
+		return "config"
+	case userData:
+		return "data"
+	case userCache:
+		return "cache"
+	case userState:
+		return "state"
+	case userLog:
+		return "log"
+	case userRuntime:
+		return "runtime"
+	case systemConfig, systemData, systemCache, systemState, systemLog, systemRuntime:
+		return filepath.Join("system", rootSubdir(dt-systemConfig+userConfig))
+	case managedRequired, managedRecommended:
+		return ""
+	default:
+		return ""
+	}
+}
+
 // ---------------------------------------------------------------------
 // Internal: path construction helpers
 // ---------------------------------------------------------------------
 
-func (d *PlatformDirs) appPath() string {
-	base := d.cfg.AppName
-	if d.cfg.Version != "" {
-		base = filepath.Join(base, d.cfg.Version)
+// appPath returns the app name followed by appSubpath.
+func (d *PlatformDirs) appPath(dt dirType) string {
+	return filepath.Join(d.cfg.AppName, d.appSubpath(dt))
+}
+
+// appSubpath returns Version unless Config.Unversioned lists dt, followed
+// by the active profile's subdirectory. It is empty when neither applies.
+func (d *PlatformDirs) appSubpath(dt dirType) string {
+	var version string
+	if d.cfg.Version != "" && !slices.Contains(d.cfg.Unversioned, dt.String()) {
+		version = d.cfg.Version
 	}
-	return base
+	return filepath.Join(version, d.profileSubdir(dt))
+}
+
+// tempRuntimeDir returns the Linux/BSD runtime fallback used when
+// XDG_RUNTIME_DIR is unset: {tmp}/{app}-{uid}, made per-user by the UID
+// because the temp directory is shared, followed by appSubpath.
+func (d *PlatformDirs) tempRuntimeDir() string {
+	dir := fmt.Sprintf("%s-%d", d.cfg.AppName, d.uid())
+	return filepath.Join(d.tempDir(), dir, d.appSubpath(userRuntime))
 }
 
-func (d *PlatformDirs) windowsAppPath() string {
+func (d *PlatformDirs) windowsAppPath(dt dirType) string {
 	if d.cfg.AppAuthor != "" {
-		return filepath.Join(d.cfg.AppAuthor, d.appPath())
+		return filepath.Join(d.cfg.AppAuthor, d.appPath(dt))
 	}
-	return d.appPath()
+	return d.appPath(dt)
 }
 
 func (d *PlatformDirs) isXDGPlatform() bool {
@@ -690,10 +919,37 @@ func (d *PlatformDirs) includeXDGFallbacks() bool {
 	return *d.cfg.IncludeXDGFallbacks
 }
 
-// userDirsWithFallbacks returns user directories with optional XDG fallbacks.
+// userDirsWithFallbacks returns platformUserDirs, with the previous
+// version's directory after the primary one when Config.VersionFallback is
+// set, followed by the legacy directories declared in Config.LegacyPaths.
+// It is the read order; Migrate picks its sources with migrationSources.
+func (d *PlatformDirs) userDirsWithFallbacks(dt dirType) []string {
+	dirs := d.platformUserDirs(dt)
+	if prev := d.fallbackVersionDir(dt); prev != "" && !slices.Contains(dirs, prev) {
+		dirs = slices.Insert(dirs, 1, prev)
+	}
+	for _, dir := range d.legacyDirs(dt) {
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// platformUserDirs returns user directories with optional XDG fallbacks.
 // On XDG platforms, returns just the primary directory.
 // On non-XDG platforms with IncludeXDGFallbacks, includes XDG defaults as fallbacks.
-func (d *PlatformDirs) userDirsWithFallbacks(dt dirType) []string {
+func (d *PlatformDirs) platformUserDirs(dt dirType) []string {
+	// A portable tree replaces the platform resolution entirely
+	if d.portable != "" {
+		return []string{d.resolveUserDirForFallbacks(dt)}
+	}
+
+	// systemd directories replace the platform resolution entirely
+	if systemd := d.systemdDirs(dt); len(systemd) > 0 {
+		return systemd
+	}
+
 	primary := d.resolveUserDirForFallbacks(dt)
 
 	// On XDG platforms or with XDGOnAllPlatforms, no fallbacks needed
@@ -718,7 +974,7 @@ func (d *PlatformDirs) userDirsWithFallbacks(dt dirType) []string {
 // resolveUserDirForFallbacks resolves the primary user directory.
 // Unlike resolveUserDir, this doesn't use env overrides (those are handled separately).
 func (d *PlatformDirs) resolveUserDirForFallbacks(dt dirType) string {
-	if dir := d.fromEnvOverride(dt); dir != "" {
+	if dir := d.overrideDir(dt); dir != "" {
 		return dir
 	}
 	return d.resolveUserDir(dt)
@@ -727,19 +983,19 @@ func (d *PlatformDirs) resolveUserDirForFallbacks(dt dirType) string {
 // xdgUserDirDefault returns the XDG default path for a user directory type.
 // This returns the default without checking XDG env vars.
 func (d *PlatformDirs) xdgUserDirDefault(dt dirType) string {
-	home := userHomeDir()
+	home := d.homeDir()
 
 	switch dt { //nolint:exhaustive // only user dir types are supported
 	case userConfig:
-		return filepath.Join(home, ".config", d.appPath())
+		return filepath.Join(home, ".config", d.appPath(dt))
 	case userData:
-		return filepath.Join(home, ".local", "share", d.appPath())
+		return filepath.Join(home, ".local", "share", d.appPath(dt))
 	case userCache:
-		return filepath.Join(home, ".cache", d.appPath())
+		return filepath.Join(home, ".cache", d.appPath(dt))
 	case userState:
-		return filepath.Join(home, ".local", "state", d.appPath())
+		return filepath.Join(home, ".local", "state", d.appPath(dt))
 	case userLog:
-		stateDir := filepath.Join(home, ".local", "state", d.appPath())
+		stateDir := filepath.Join(home, ".local", "state", d.appPath(dt))
 		return filepath.Join(stateDir, "log")
 	default:
 		return ""
@@ -765,13 +1021,57 @@ const (
 	systemState
 	systemLog
 	systemRuntime
+	managedRequired
+	managedRecommended
 )
 
+// String returns the kebab-case name of the directory type, as used in
+// diagnostics output (e.g., "user-config").
+func (dt dirType) String() string {
+	switch dt {
+	case userConfig:
+		return "user-config"
+	case userData:
+		return "user-data"
+	case userCache:
+		return "user-cache"
+	case userState:
+		return "user-state"
+	case userLog:
+		return "user-log"
+	case userRuntime:
+		return "user-runtime"
+	case systemConfig:
+		return "system-config"
+	case systemData:
+		return "system-data"
+	case systemCache:
+		return "system-cache"
+	case systemState:
+		return "system-state"
+	case systemLog:
+		return "system-log"
+	case systemRuntime:
+		return "system-runtime"
+	case managedRequired:
+		return "managed-required"
+	case managedRecommended:
+		return "managed-recommended"
+	default:
+		return "unknown"
+	}
+}
+
 // ---------------------------------------------------------------------
 // Internal: user directory resolution
 // ---------------------------------------------------------------------
 
 func (d *PlatformDirs) resolveUserDir(dt dirType) string {
+	// Sandbox conventions replace the platform ones
+	if dirs := d.sandboxDirs(dt); len(dirs) > 0 {
+		return dirs[0]
+	}
+
 	// On XDG platforms, always use XDG
 	if d.isXDGPlatform() {
 		return d.xdgUserDir(dt)
@@ -801,37 +1101,40 @@ func (d *PlatformDirs) resolveUserDir(dt dirType) string {
 }
 
 func (d *PlatformDirs) xdgUserDir(dt dirType) string {
-	home := userHomeDir()
+	home := d.homeDir()
 
 	switch dt { //nolint:exhaustive // only user dir types are supported
 	case userConfig:
-		if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
-			return filepath.Join(dir, d.appPath())
+		if dir := d.xdgEnv("XDG_CONFIG_HOME"); dir != "" {
+			return filepath.Join(dir, d.appPath(dt))
 		}
-		return filepath.Join(home, ".config", d.appPath())
+		return filepath.Join(home, ".config", d.appPath(dt))
 
 	case userData:
-		if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
-			return filepath.Join(dir, d.appPath())
+		if dir := d.xdgEnv("XDG_DATA_HOME"); dir != "" {
+			return filepath.Join(dir, d.appPath(dt))
 		}
-		return filepath.Join(home, ".local", "share", d.appPath())
+		return filepath.Join(home, ".local", "share", d.appPath(dt))
 
 	case userCache:
-		if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
-			return filepath.Join(dir, d.appPath())
+		if dir := d.xdgEnv("XDG_CACHE_HOME"); dir != "" {
+			return filepath.Join(dir, d.appPath(dt))
 		}
-		return filepath.Join(home, ".cache", d.appPath())
+		return filepath.Join(home, ".cache", d.appPath(dt))
 
 	case userState:
-		if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
-			return filepath.Join(dir, d.appPath())
+		if dir := d.xdgEnv("XDG_STATE_HOME"); dir != "" {
+			return filepath.Join(dir, d.appPath(dt))
 		}
-		return filepath.Join(home, ".local", "state", d.appPath())
+		return filepath.Join(home, ".local", "state", d.appPath(dt))
 
 	case userLog:
-		// XDG doesn't define a log dir; convention is state/log
-		stateDir := d.xdgUserDir(userState)
-		return filepath.Join(stateDir, "log")
+		// XDG doesn't define a log dir; convention is state/log. The app
+		// path is the log's own, so Unversioned and Unprofiled apply.
+		if dir := d.xdgEnv("XDG_STATE_HOME"); dir != "" {
+			return filepath.Join(dir, d.appPath(dt), "log")
+		}
+		return filepath.Join(home, ".local", "state", d.appPath(dt), "log")
 
 	default:
 		return ""
@@ -851,31 +1154,31 @@ func (d *PlatformDirs) xdgUserDirEnvOnly(dt dirType) string {
 	case userState:
 		envVar = "XDG_STATE_HOME"
 	case userLog:
-		if state := os.Getenv("XDG_STATE_HOME"); state != "" {
-			return filepath.Join(state, d.appPath(), "log")
+		if state := d.xdgEnv("XDG_STATE_HOME"); state != "" {
+			return filepath.Join(state, d.appPath(dt), "log")
 		}
 		return ""
 	default:
 		return ""
 	}
 
-	if dir := os.Getenv(envVar); dir != "" {
-		return filepath.Join(dir, d.appPath())
+	if dir := d.xdgEnv(envVar); dir != "" {
+		return filepath.Join(dir, d.appPath(dt))
 	}
 	return ""
 }
 
 func (d *PlatformDirs) macOSUserDir(dt dirType) string {
-	home := userHomeDir()
+	home := d.homeDir()
 	lib := filepath.Join(home, "Library")
 
 	switch dt { //nolint:exhaustive // only user dir types are supported
 	case userConfig, userData, userState:
-		return filepath.Join(lib, "Application Support", d.appPath())
+		return filepath.Join(lib, "Application Support", d.appPath(dt))
 	case userCache:
-		return filepath.Join(lib, "Caches", d.appPath())
+		return filepath.Join(lib, "Caches", d.appPath(dt))
 	case userLog:
-		return filepath.Join(lib, "Logs", d.appPath())
+		return filepath.Join(lib, "Logs", d.appPath(dt))
 	default:
 		return ""
 	}
@@ -887,19 +1190,19 @@ func (d *PlatformDirs) windowsUserDir(dt dirType) string {
 	switch dt { //nolint:exhaustive // only user dir types are supported
 	case userConfig, userData, userState:
 		if d.cfg.Roaming {
-			baseDir = windowsRoamingAppData()
+			baseDir = d.windowsRoamingAppData()
 		} else {
-			baseDir = windowsLocalAppData()
+			baseDir = d.windowsLocalAppData()
 		}
-		return filepath.Join(baseDir, d.windowsAppPath())
+		return filepath.Join(baseDir, d.windowsAppPath(dt))
 
 	case userCache:
-		baseDir = windowsLocalAppData()
-		return filepath.Join(baseDir, d.windowsAppPath(), "cache")
+		baseDir = d.windowsLocalAppData()
+		return filepath.Join(baseDir, d.windowsAppPath(dt), "cache")
 
 	case userLog:
-		baseDir = windowsLocalAppData()
-		return filepath.Join(baseDir, d.windowsAppPath(), "log")
+		baseDir = d.windowsLocalAppData()
+		return filepath.Join(baseDir, d.windowsAppPath(dt), "log")
 
 	default:
 		return ""
@@ -911,16 +1214,16 @@ func (d *PlatformDirs) windowsUserDir(dt dirType) string {
 // ---------------------------------------------------------------------
 
 func (d *PlatformDirs) resolveRuntimeDir() (string, error) {
-	// Check XDG env var first (on XDG platforms or if XDGOnAllPlatforms)
-	if d.isXDGPlatform() || d.cfg.XDGOnAllPlatforms {
-		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
-			return filepath.Join(dir, d.appPath()), nil
-		}
+	if dirs := d.sandboxDirs(userRuntime); len(dirs) > 0 {
+		return dirs[0], nil
 	}
 
-	// Also check on non-XDG platforms if env var is explicitly set
-	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
-		return filepath.Join(dir, d.appPath()), nil
+	// XDG_RUNTIME_DIR is respected on every platform when explicitly set
+	if dir := d.xdgEnv("XDG_RUNTIME_DIR"); dir != "" {
+		if err := d.checkRuntimeDir(dir); err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, d.appPath(userRuntime)), nil
 	}
 
 	switch d.platform {
@@ -928,14 +1231,20 @@ func (d *PlatformDirs) resolveRuntimeDir() (string, error) {
 		// XDG_RUNTIME_DIR not set - fall back to temp directory
 		// Note: This is technically non-compliant with XDG spec which says
 		// the dir should not persist across reboots, but temp is reasonable
-		return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d", d.cfg.AppName, os.Getuid())), nil
+		if d.cfg.StrictXDG {
+			d.reportXDG(&XDGViolation{
+				EnvVar: "XDG_RUNTIME_DIR",
+				Reason: "unset; falling back to a temporary directory that persists across logins",
+			})
+		}
+		return d.tempRuntimeDir(), nil
 
 	case PlatformMacOS:
 		// $TMPDIR is per-user on macOS
-		return filepath.Join(os.TempDir(), d.appPath()), nil
+		return filepath.Join(d.tempDir(), d.appPath(userRuntime)), nil
 
 	case PlatformWindows:
-		return filepath.Join(windowsLocalAppData(), d.windowsAppPath(), "runtime"), nil
+		return filepath.Join(d.windowsLocalAppData(), d.windowsAppPath(userRuntime), "runtime"), nil
 
 	case PlatformAuto:
 		// PlatformAuto is resolved to a concrete platform in NewWithConfig.
@@ -950,6 +1259,10 @@ func (d *PlatformDirs) resolveRuntimeDir() (string, error) {
 // ---------------------------------------------------------------------
 
 func (d *PlatformDirs) resolveSystemDirs(dt dirType) []string {
+	if dirs := d.sandboxDirs(dt); len(dirs) > 0 {
+		return dirs
+	}
+
 	if d.isXDGPlatform() {
 		return d.xdgSystemDirs(dt)
 	}
@@ -988,17 +1301,9 @@ func (d *PlatformDirs) xdgSystemDirs(dt dirType) []string {
 		return nil
 	}
 
-	val := os.Getenv(envVar)
-	if val == "" {
-		val = defaultVal
-	}
-
-	parts := strings.Split(val, ":")
 	var dirs []string
-	for _, p := range parts {
-		if p != "" {
-			dirs = append(dirs, filepath.Join(p, d.appPath()))
-		}
+	for _, dir := range d.xdgEnvList(envVar, defaultVal) {
+		dirs = append(dirs, filepath.Join(dir, d.appPath(dt)))
 	}
 	return dirs
 }
@@ -1014,17 +1319,13 @@ func (d *PlatformDirs) xdgSystemDirsEnvOnly(dt dirType) []string {
 		return nil
 	}
 
-	val := os.Getenv(envVar)
-	if val == "" {
+	if d.getenv(envVar) == "" {
 		return nil
 	}
 
-	parts := strings.Split(val, ":")
 	var dirs []string
-	for _, p := range parts {
-		if p != "" {
-			dirs = append(dirs, filepath.Join(p, d.appPath()))
-		}
+	for _, dir := range d.xdgEnvList(envVar, "") {
+		dirs = append(dirs, filepath.Join(dir, d.appPath(dt)))
 	}
 	return dirs
 }
@@ -1032,7 +1333,7 @@ func (d *PlatformDirs) xdgSystemDirsEnvOnly(dt dirType) []string {
 func (d *PlatformDirs) macOSSystemDirs(dt dirType) []string {
 	switch dt { //nolint:exhaustive // only system config/data use search paths
 	case systemConfig, systemData:
-		return []string{filepath.Join("/Library", "Application Support", d.appPath())}
+		return []string{filepath.Join("/Library", "Application Support", d.appPath(dt))}
 	default:
 		return nil
 	}
@@ -1041,7 +1342,7 @@ func (d *PlatformDirs) macOSSystemDirs(dt dirType) []string {
 func (d *PlatformDirs) windowsSystemDirs(dt dirType) []string {
 	switch dt { //nolint:exhaustive // only system config/data use search paths
 	case systemConfig, systemData:
-		return []string{filepath.Join(windowsProgramData(), d.windowsAppPath())}
+		return []string{filepath.Join(d.windowsProgramData(), d.windowsAppPath(dt))}
 	default:
 		return nil
 	}
@@ -1052,6 +1353,15 @@ func (d *PlatformDirs) windowsSystemDirs(dt dirType) []string {
 // ---------------------------------------------------------------------
 
 func (d *PlatformDirs) resolveSystemSingleDir(dt dirType) string {
+	if dirs := d.sandboxDirs(dt); len(dirs) > 0 {
+		return dirs[0]
+	}
+	return d.nativeSystemSingleDir(dt)
+}
+
+// nativeSystemSingleDir returns the platform convention for a single
+// system directory, ignoring any sandbox.
+func (d *PlatformDirs) nativeSystemSingleDir(dt dirType) string {
 	switch d.platform { //nolint:exhaustive // PlatformAuto resolved during construction
 	case PlatformLinux, PlatformFreeBSD, PlatformOpenBSD:
 		return d.fhsSystemDir(dt)
@@ -1067,13 +1377,13 @@ func (d *PlatformDirs) resolveSystemSingleDir(dt dirType) string {
 func (d *PlatformDirs) fhsSystemDir(dt dirType) string {
 	switch dt { //nolint:exhaustive // only system single-dir types
 	case systemCache:
-		return filepath.Join("/var", "cache", d.appPath())
+		return filepath.Join("/var", "cache", d.appPath(dt))
 	case systemState:
-		return filepath.Join("/var", "lib", d.appPath())
+		return filepath.Join("/var", "lib", d.appPath(dt))
 	case systemLog:
-		return filepath.Join("/var", "log", d.appPath())
+		return filepath.Join("/var", "log", d.appPath(dt))
 	case systemRuntime:
-		return filepath.Join("/run", d.appPath())
+		return filepath.Join("/run", d.appPath(dt))
 	default:
 		return ""
 	}
@@ -1082,12 +1392,12 @@ func (d *PlatformDirs) fhsSystemDir(dt dirType) string {
 func (d *PlatformDirs) macOSSystemSingleDir(dt dirType) string {
 	switch dt { //nolint:exhaustive // only system single-dir types
 	case systemCache:
-		return filepath.Join("/Library", "Caches", d.appPath())
+		return filepath.Join("/Library", "Caches", d.appPath(dt))
 	case systemState:
 		// macOS doesn't distinguish state from data at the system level
-		return filepath.Join("/Library", "Application Support", d.appPath())
+		return filepath.Join("/Library", "Application Support", d.appPath(dt))
 	case systemLog:
-		return filepath.Join("/Library", "Logs", d.appPath())
+		return filepath.Join("/Library", "Logs", d.appPath(dt))
 	case systemRuntime:
 		// No equivalent on macOS
 		return ""
@@ -1097,8 +1407,8 @@ func (d *PlatformDirs) macOSSystemSingleDir(dt dirType) string {
 }
 
 func (d *PlatformDirs) windowsSystemSingleDir(dt dirType) string {
-	programData := windowsProgramData()
-	base := filepath.Join(programData, d.windowsAppPath())
+	programData := d.windowsProgramData()
+	base := filepath.Join(programData, d.windowsAppPath(dt))
 
 	switch dt { //nolint:exhaustive // only system single-dir types
 	case systemCache:
@@ -1121,6 +1431,21 @@ func (d *PlatformDirs) windowsSystemSingleDir(dt dirType) string {
 // ---------------------------------------------------------------------
 
 func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+	return fsExists(osFS{}, path)
+}
+
+func dirExists(path string) bool {
+	return fsDirExists(osFS{}, path)
+}
+
+// fileExists reports whether path exists in the configured FS.
+func (d *PlatformDirs) fileExists(path string) bool {
+	return fsExists(d.fsys, path)
+}
+
+// dirExists reports whether path is a directory in the configured FS.
+// Together with fileExists, it lets cascades built on PlatformDirs use
+// the same FS.
+func (d *PlatformDirs) dirExists(path string) bool {
+	return fsDirExists(d.fsys, path)
 }
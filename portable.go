@@ -0,0 +1,122 @@
+package toolpaths
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPortableMarkers are the names checked beside the executable when
+// Portable.Markers is empty.
+var DefaultPortableMarkers = []string{"portable.txt", "data"}
+
+// defaultPortableDir is the tree portable installs keep their directories in.
+const defaultPortableDir = "data"
+
+// Portable configures portable installs, where every user directory lives
+// in a tree next to the executable instead of the platform locations.
+//
+// Portable mode is enabled when any marker exists in the executable's
+// directory, or when EnvVar forces it. The user directories then resolve
+// to {root}/config, {root}/data, {root}/cache, {root}/state, {root}/log,
+// and {root}/runtime, where root is {executable dir}/{Dir}. The app name
+// is not appended: the tree belongs to the executable. System and managed
+// directories are unaffected, and EnvOverrides still take precedence.
+type Portable struct {
+	// Markers are file or directory names checked in the executable's
+	// directory. Default: DefaultPortableMarkers.
+	Markers []string
+
+	// EnvVar optionally names a variable that forces the mode: "1", "true",
+	// or "yes" enable it, "0", "false", or "no" disable it even if a marker
+	// exists, and an absolute path enables it with that path as the root.
+	EnvVar string
+
+	// Dir is the portable tree, relative to the executable's directory.
+	// Default: "data".
+	Dir string
+
+	// ExecutableDir replaces the directory of os.Executable(). Useful for
+	// testing.
+	ExecutableDir string
+}
+
+// PortableRoot returns the root of the portable tree, or false if portable
+// mode is not enabled. The mode is detected when the PlatformDirs is
+// created; see Config.Portable.
+func (d *PlatformDirs) PortableRoot() (string, bool) {
+	return d.portable, d.portable != ""
+}
+
+// ---------------------------------------------------------------------
+// Internal: portable detection and resolution
+// ---------------------------------------------------------------------
+
+// detectPortable returns the portable root, or "" if the mode is off.
+func (d *PlatformDirs) detectPortable() string {
+	pc := d.cfg.Portable
+	if pc == nil {
+		return ""
+	}
+
+	exeDir := pc.ExecutableDir
+	if exeDir == "" {
+		exeDir = executableDir()
+	}
+	dir := pc.Dir
+	if dir == "" {
+		dir = defaultPortableDir
+	}
+
+	if pc.EnvVar != "" {
+		value := d.getenv(pc.EnvVar)
+		switch strings.ToLower(value) {
+		case "1", "true", "yes":
+			if exeDir == "" {
+				return ""
+			}
+			return filepath.Join(exeDir, dir)
+		case "0", "false", "no":
+			return ""
+		}
+		if filepath.IsAbs(value) {
+			return filepath.Clean(value)
+		}
+	}
+
+	if exeDir == "" {
+		return ""
+	}
+	markers := pc.Markers
+	if len(markers) == 0 {
+		markers = DefaultPortableMarkers
+	}
+	for _, marker := range markers {
+		if fsExists(d.fsys, filepath.Join(exeDir, marker)) {
+			return filepath.Join(exeDir, dir)
+		}
+	}
+	return ""
+}
+
+// executableDir returns the directory of the running executable with
+// symlinks resolved, or "" if it cannot be determined.
+func executableDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe)
+}
+
+// portableDir returns the portable directory for dt, or "" if portable
+// mode is off or does not cover dt.
+func (d *PlatformDirs) portableDir(dt dirType) string {
+	if d.portable == "" || dt > userRuntime {
+		return ""
+	}
+	return filepath.Join(d.portable, rootSubdir(dt), d.profileSubdir(dt))
+}
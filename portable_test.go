@@ -0,0 +1,158 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestPortableMarkers(t *testing.T) {
+	setTestHomeXDG(t)
+	exeDir := p(testBase(), "usb", "tool")
+	root := p(exeDir, "data")
+
+	t.Run("no marker", func(t *testing.T) {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:  "myapp",
+			Platform: toolpaths.PlatformWindows,
+			FS:       toolpaths.NewMapFS(),
+			Portable: &toolpaths.Portable{ExecutableDir: exeDir},
+		})
+		require.NoError(t, err)
+		_, ok := dirs.PortableRoot()
+		assert.False(t, ok)
+		assert.NotContains(t, dirs.UserConfigDir(), exeDir)
+	})
+
+	t.Run("portable.txt", func(t *testing.T) {
+		mem := toolpaths.NewMapFS()
+		require.NoError(t, mem.WriteFile(p(exeDir, "portable.txt"), nil, 0o644))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:  "myapp",
+			Platform: toolpaths.PlatformWindows,
+			FS:       mem,
+			Portable: &toolpaths.Portable{ExecutableDir: exeDir},
+		})
+		require.NoError(t, err)
+
+		got, ok := dirs.PortableRoot()
+		require.True(t, ok)
+		assert.Equal(t, root, got)
+		assert.Equal(t, p(root, "config"), dirs.UserConfigDir())
+		assert.Equal(t, []string{p(root, "config")}, dirs.UserConfigDirs(), "no XDG fallback")
+		assert.Equal(t, p(root, "data"), dirs.UserDataDir())
+		assert.Equal(t, p(root, "cache"), dirs.UserCacheDir())
+		assert.Equal(t, p(root, "state"), dirs.UserStateDir())
+		assert.Equal(t, p(root, "log"), dirs.UserLogDir())
+		rt, err := dirs.UserRuntimeDir()
+		require.NoError(t, err)
+		assert.Equal(t, p(root, "runtime"), rt)
+		assert.NotContains(t, dirs.SystemConfigDir(), exeDir, "system directories are unaffected")
+
+		dir, err := dirs.EnsureUserStateDir()
+		require.NoError(t, err)
+		assert.Equal(t, p(root, "state"), dir)
+		info, err := mem.Stat(dir)
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+	})
+
+	t.Run("data directory", func(t *testing.T) {
+		mem := toolpaths.NewMapFS()
+		require.NoError(t, mem.MkdirAll(root, 0o755))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:  "myapp",
+			Platform: toolpaths.PlatformWindows,
+			FS:       mem,
+			Portable: &toolpaths.Portable{ExecutableDir: exeDir},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, p(root, "cache"), dirs.UserCacheDir())
+	})
+
+	t.Run("custom marker and dir", func(t *testing.T) {
+		mem := toolpaths.NewMapFS()
+		require.NoError(t, mem.WriteFile(p(exeDir, ".portable"), nil, 0o644))
+		require.NoError(t, mem.WriteFile(p(exeDir, "portable.txt"), nil, 0o644))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:  "myapp",
+			Platform: toolpaths.PlatformWindows,
+			FS:       mem,
+			Portable: &toolpaths.Portable{ExecutableDir: exeDir, Markers: []string{".portable"}, Dir: "profile"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, p(exeDir, "profile", "config"), dirs.UserConfigDir())
+	})
+}
+
+func TestPortableEnvVar(t *testing.T) {
+	home := setTestHomeXDG(t)
+	exeDir := p(testBase(), "usb", "tool")
+	marked := toolpaths.NewMapFS()
+	require.NoError(t, marked.WriteFile(p(exeDir, "portable.txt"), nil, 0o644))
+	pc := toolpaths.Portable{EnvVar: "MYAPP_PORTABLE", ExecutableDir: exeDir}
+
+	t.Setenv("MYAPP_PORTABLE", "true")
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformWindows,
+		FS:       toolpaths.NewMapFS(),
+		Portable: &pc,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, p(exeDir, "data", "config"), dirs.UserConfigDir())
+
+	t.Setenv("MYAPP_PORTABLE", "0")
+	dirs, err = toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformWindows,
+		FS:       marked,
+		Portable: &pc,
+	})
+	require.NoError(t, err)
+	_, ok := dirs.PortableRoot()
+	assert.False(t, ok, "env var disables a marker")
+
+	t.Setenv("MYAPP_PORTABLE", p(home, "stick"))
+	dirs, err = toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformWindows,
+		FS:       toolpaths.NewMapFS(),
+		Portable: &pc,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, p(home, "stick", "log"), dirs.UserLogDir())
+
+	t.Run("EnvOverrides win", func(t *testing.T) {
+		t.Setenv("MYAPP_CONFIG", p(home, "override"))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:      "myapp",
+			Portable:     &pc,
+			EnvOverrides: &toolpaths.EnvOverrides{UserConfig: "MYAPP_CONFIG"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, p(home, "override"), dirs.UserConfigDir())
+		assert.Equal(t, p(home, "stick", "data"), dirs.UserDataDir())
+	})
+
+	t.Run("explain and diagnose", func(t *testing.T) {
+		x, err := dirs.Explain("user-data")
+		require.NoError(t, err)
+		step, ok := x.Chosen()
+		require.True(t, ok)
+		assert.Equal(t, toolpaths.StrategyPortable, step.Strategy)
+		assert.Equal(t, x.Path, step.Path)
+
+		diag := dirs.Diagnose()
+		assert.Equal(t, p(home, "stick"), diag.Portable)
+		assert.Contains(t, diag.String(), "Portable:  "+p(home, "stick"))
+		var names []string
+		for _, v := range diag.Env {
+			names = append(names, v.Name)
+		}
+		assert.Contains(t, names, "MYAPP_PORTABLE")
+	})
+}
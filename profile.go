@@ -0,0 +1,86 @@
+package toolpaths
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidProfile is returned by NewWithConfig when the profile name is
+// not a single, non-hidden path element.
+var ErrInvalidProfile = errors.New("toolpaths: invalid profile name")
+
+// profilesDir is the directory profiles live in below the app path.
+const profilesDir = "profiles"
+
+// Profile returns the active profile name, or "" if none is selected. See
+// Config.Profile.
+func (d *PlatformDirs) Profile() string {
+	return d.profile
+}
+
+// Profiles returns the names of the profiles that have a directory in any
+// profiled user directory kind, sorted. It lists existing profiles whether
+// or not one is active, so a CLI can offer them for --profile.
+func (d *PlatformDirs) Profiles() []string {
+	var names []string
+	for _, dt := range []dirType{userConfig, userData, userCache, userState, userLog} {
+		setPlaceholder := func(c *PlatformDirs) { c.profile = profilePlaceholder }
+		parent, suffix, ok := d.splitPath(dt, profilePlaceholder, setPlaceholder)
+		if !ok {
+			continue
+		}
+		for _, name := range readDirNames(d.fsys, parent) {
+			if validProfile(name) != nil || slices.Contains(names, name) {
+				continue
+			}
+			if fi, err := d.fsys.Stat(filepath.Join(parent, name, suffix)); err == nil && fi.IsDir() {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ---------------------------------------------------------------------
+// Internal: profile resolution
+// ---------------------------------------------------------------------
+
+// profilePlaceholder stands in for the profile name when Profiles looks for
+// where a resolved path puts it.
+const profilePlaceholder = "\x00profile"
+
+// detectProfile returns Config.Profile, or else the value of ProfileEnv.
+func (d *PlatformDirs) detectProfile() (string, error) {
+	name := d.cfg.Profile
+	if name == "" && d.cfg.ProfileEnv != "" {
+		name = d.getenv(d.cfg.ProfileEnv)
+	}
+	if name == "" {
+		return "", nil
+	}
+	if err := validProfile(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// validProfile reports whether name can be used as a profile directory.
+func validProfile(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`+"\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidProfile, name)
+	}
+	return nil
+}
+
+// profileSubdir returns profiles/{name} for the user directory kinds the
+// active profile applies to, and "" otherwise.
+func (d *PlatformDirs) profileSubdir(dt dirType) string {
+	if d.profile == "" || dt > userRuntime || slices.Contains(d.cfg.Unprofiled, dt.String()) {
+		return ""
+	}
+	return filepath.Join(profilesDir, d.profile)
+}
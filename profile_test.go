@@ -0,0 +1,200 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestProfileDirs(t *testing.T) {
+	home := p(testBase(), "home")
+	runtimeBase := p(testBase(), "run")
+	base := toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		HomeDir:   home,
+		LookupEnv: mapEnv(nil),
+		Profile:   "work",
+	}
+	cfg := base
+	cfg.LookupEnv = mapEnv(map[string]string{"XDG_RUNTIME_DIR": runtimeBase})
+	dirs, err := toolpaths.NewWithConfig(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, "work", dirs.Profile())
+	assert.Equal(t, p(home, ".config", "myapp", "profiles", "work"), dirs.UserConfigDir())
+	assert.Equal(t, p(home, ".local", "share", "myapp", "profiles", "work"), dirs.UserDataDir())
+	assert.Equal(t, p(home, ".cache", "myapp", "profiles", "work"), dirs.UserCacheDir())
+	assert.Equal(t, p(home, ".local", "state", "myapp", "profiles", "work"), dirs.UserStateDir())
+	assert.Equal(t, p(home, ".local", "state", "myapp", "profiles", "work", "log"), dirs.UserLogDir())
+	rt, err := dirs.UserRuntimeDir()
+	require.NoError(t, err)
+	assert.Equal(t, p(runtimeBase, "myapp", "profiles", "work"), rt)
+	assert.Equal(t, p(home, ".config", "myapp", "profiles", "work", "config.toml"), dirs.UserConfigPath("config.toml"))
+	assert.Equal(t, p("/etc", "xdg", "myapp"), dirs.SystemConfigDirs()[0], "system directories are shared")
+
+	t.Run("unprofiled kinds are shared", func(t *testing.T) {
+		cfg := base
+		cfg.Unprofiled = []string{"user-cache"}
+		dirs, err := toolpaths.NewWithConfig(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, p(home, ".cache", "myapp"), dirs.UserCacheDir())
+		assert.Equal(t, p(home, ".config", "myapp", "profiles", "work"), dirs.UserConfigDir())
+
+		cfg.Unprofiled = []string{"user-log"}
+		dirs, err = toolpaths.NewWithConfig(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, p(home, ".local", "state", "myapp", "log"), dirs.UserLogDir())
+		assert.Equal(t, p(home, ".local", "state", "myapp", "profiles", "work"), dirs.UserStateDir())
+	})
+
+	t.Run("with version", func(t *testing.T) {
+		cfg := base
+		cfg.Version = "3"
+		dirs, err := toolpaths.NewWithConfig(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, p(home, ".config", "myapp", "3", "profiles", "work"), dirs.UserConfigDir())
+	})
+
+	t.Run("runtime temp fallback", func(t *testing.T) {
+		cfg := base
+		cfg.Version = "3"
+		cfg.TempDir = p(testBase(), "tmp")
+		cfg.UID = func() int { return 1000 }
+		dirs, err := toolpaths.NewWithConfig(cfg)
+		require.NoError(t, err)
+		rt, err := dirs.UserRuntimeDir()
+		require.NoError(t, err)
+		assert.Equal(t, p(testBase(), "tmp", "myapp-1000", "3", "profiles", "work"), rt)
+
+		x, err := dirs.Explain("user-runtime")
+		require.NoError(t, err)
+		chosen, ok := x.Chosen()
+		require.True(t, ok)
+		assert.Equal(t, rt, chosen.Path)
+	})
+
+	t.Run("single-root override", func(t *testing.T) {
+		root := p(testBase(), "myapp-home")
+		cfg := base
+		cfg.EnvPrefix = "MYAPP"
+		cfg.LookupEnv = mapEnv(map[string]string{
+			"MYAPP_HOME":       root,
+			"MYAPP_CACHE_HOME": p(testBase(), "cache"),
+		})
+		dirs, err := toolpaths.NewWithConfig(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, p(root, "config", "profiles", "work"), dirs.UserConfigDir())
+		assert.Equal(t, p(testBase(), "cache", "profiles", "work"), dirs.UserCacheDir())
+	})
+}
+
+func TestProfileEnv(t *testing.T) {
+	home := p(testBase(), "home")
+
+	env := mapEnv(map[string]string{"MYAPP_PROFILE": "personal"})
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		HomeDir:   home,
+		LookupEnv: env,
+		EnvPrefix: "MYAPP",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "personal", dirs.Profile())
+	assert.Equal(t, p(home, ".config", "myapp", "profiles", "personal"), dirs.UserConfigDir())
+	assert.Contains(t, dirs.DiagnoseEnv(), toolpaths.EnvVar{Name: "MYAPP_PROFILE", Value: "personal", Set: true})
+	assert.Equal(t, "personal", dirs.Diagnose().Profile)
+
+	dirs, err = toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		HomeDir:   home,
+		LookupEnv: env,
+		EnvPrefix: "MYAPP",
+		Profile:   "work",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "work", dirs.Profile(), "Config.Profile, as from a --profile flag, wins")
+
+	dirs, err = toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:    "myapp",
+		Platform:   toolpaths.PlatformLinux,
+		HomeDir:    home,
+		LookupEnv:  mapEnv(nil),
+		ProfileEnv: "MYAPP_PROFILE",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, dirs.Profile())
+	assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir())
+}
+
+func TestProfileInvalid(t *testing.T) {
+	for _, name := range []string{"..", ".hidden", "a/b", `a\b`} {
+		_, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Profile: name})
+		require.ErrorIs(t, err, toolpaths.ErrInvalidProfile, name)
+	}
+	_, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName: "myapp", ProfileEnv: "MYAPP_PROFILE", LookupEnv: mapEnv(map[string]string{"MYAPP_PROFILE": "../etc"}),
+	})
+	require.ErrorIs(t, err, toolpaths.ErrInvalidProfile)
+
+	for _, kind := range []string{"cache", "system-config", "managed-required"} {
+		_, err = toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Unprofiled: []string{kind}})
+		require.ErrorIs(t, err, toolpaths.ErrUnknownKind, kind)
+	}
+}
+
+func TestProfileFindConfigFile(t *testing.T) {
+	home := p(testBase(), "home")
+	mem := toolpaths.NewMapFS()
+	require.NoError(t, mem.WriteFile(p(home, ".config", "myapp", "config.toml"), nil, 0o600))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		HomeDir:   home,
+		LookupEnv: mapEnv(nil),
+		FS:        mem,
+		Profile:   "work",
+	})
+	require.NoError(t, err)
+	_, ok := dirs.FindConfigFile("config.toml")
+	assert.False(t, ok, "profiles do not see each other's or the base config")
+
+	require.NoError(t, mem.WriteFile(p(home, ".config", "myapp", "profiles", "work", "config.toml"), nil, 0o600))
+	got, ok := dirs.FindConfigFile("config.toml")
+	require.True(t, ok)
+	assert.Equal(t, p(home, ".config", "myapp", "profiles", "work", "config.toml"), got)
+}
+
+func TestProfiles(t *testing.T) {
+	home := p(testBase(), "home")
+	mem := toolpaths.NewMapFS()
+	require.NoError(t, mem.MkdirAll(p(home, ".config", "myapp", "profiles", "work"), 0o700))
+	require.NoError(t, mem.MkdirAll(p(home, ".config", "myapp", "profiles", "personal"), 0o700))
+	require.NoError(t, mem.MkdirAll(p(home, ".config", "myapp", "profiles", ".trash"), 0o700))
+	require.NoError(t, mem.WriteFile(p(home, ".config", "myapp", "profiles", "notes.txt"), nil, 0o600))
+	require.NoError(t, mem.MkdirAll(p(home, ".local", "share", "myapp", "profiles", "archive"), 0o700))
+	require.NoError(t, mem.MkdirAll(p(home, ".cache", "myapp", "profiles", "scratch"), 0o700))
+
+	cfg := toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		HomeDir:   home,
+		LookupEnv: mapEnv(nil),
+		FS:        mem,
+	}
+	dirs, err := toolpaths.NewWithConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"archive", "personal", "scratch", "work"}, dirs.Profiles())
+
+	cfg.Profile = "work"
+	cfg.Unprofiled = []string{"user-cache"}
+	dirs, err = toolpaths.NewWithConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"archive", "personal", "work"}, dirs.Profiles(), "shared kinds hold no profiles")
+}
@@ -0,0 +1,379 @@
+package toolpaths
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// Project detection
+// ---------------------------------------------------------------------
+
+// ProjectRoot returns the detected project root, or empty string if none found.
+// Returns an error only if the working directory cannot be determined.
+//
+// Detection is config-dir aware: when the working directory is inside one of
+// the ConfigDirPatterns (e.g., repo/.myapp/hooks), the directory containing
+// that pattern is the root, even if a parent directory has a project marker.
+// Otherwise the detector walks up from the working directory looking for
+// ProjectMarkers, bounded by StopMarkers.
+//
+// Results are cached per working directory. Use ClearProjectRootCache if
+// markers may have been created or removed since the first call.
+func (c *Cascade) ProjectRoot() (string, error) {
+	if c.cfg.DisableProjectDetection {
+		return "", nil
+	}
+	cwd, err := c.cfg.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("toolpaths: get working directory: %w", err)
+	}
+	cwd = cleanAbsPath(cwd)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if root, ok := c.projectRoots[cwd]; ok {
+		return root, nil
+	}
+	root := c.detectProjectRoot(cwd)
+	c.projectRoots[cwd] = root
+	return root, nil
+}
+
+// IsInsideProject returns true if a project root was detected.
+func (c *Cascade) IsInsideProject() bool {
+	root, err := c.ProjectRoot()
+	return err == nil && root != ""
+}
+
+// ClearProjectRootCache discards cached project roots so the next call to
+// ProjectRoot detects again.
+func (c *Cascade) ClearProjectRootCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.projectRoots)
+}
+
+// JoinProject joins path elements to the project root.
+func (c *Cascade) JoinProject(elem ...string) (string, error) {
+	root, err := c.requireProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return path(root, elem...), nil
+}
+
+// requireProjectRoot returns the project root or ErrNoProjectRoot.
+func (c *Cascade) requireProjectRoot() (string, error) {
+	root, err := c.ProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	if root == "" {
+		return "", ErrNoProjectRoot
+	}
+	return root, nil
+}
+
+// detectProjectRoot finds the project root for cwd without consulting the cache.
+func (c *Cascade) detectProjectRoot(cwd string) string {
+	if root, found := configDirRoot(cwd, c.expandAll(c.cfg.ConfigDirPatterns)); found {
+		return root
+	}
+	root, _, found := c.dirs.FindUpUntil(cwd, c.expandAll(c.cfg.ProjectMarkers), c.cfg.StopMarkers)
+	if !found {
+		return ""
+	}
+	return root
+}
+
+// configDirRoot reports whether dir is inside (or is) one of the config
+// directory patterns and, if so, returns the directory containing the
+// pattern. When several patterns match, the one nearest to dir wins.
+func configDirRoot(dir string, patterns []string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(dir), "/")
+	best := -1
+	for _, pattern := range patterns {
+		pp := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+		// i >= 1 keeps at least the root component as the owning directory.
+		for i := len(parts) - len(pp); i >= 1 && i > best; i-- {
+			if slices.Equal(parts[i:i+len(pp)], pp) {
+				best = i
+				break
+			}
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+	root := dir
+	for range len(parts) - best {
+		root = filepath.Dir(root)
+	}
+	return root, true
+}
+
+// ---------------------------------------------------------------------
+// Project directory types
+// ---------------------------------------------------------------------
+
+// ProjectDirConfig defines a directory type at the project level.
+type ProjectDirConfig struct {
+	// Patterns are paths relative to project root, checked in order.
+	// First existing one wins for reads; first one is used for writes.
+	// Supports {app} placeholder for the app name.
+	Patterns []string
+
+	// Subdirs defines named subdirectories within this directory type.
+	// Each name maps to path variants to check (relative to the dir pattern).
+	Subdirs map[string][]string
+}
+
+// DefaultProjectDirs returns the default project directory structure for
+// CLI tools: config under .config/{app} or .{app}, and cache, data, state,
+// and log under .{app}.
+func DefaultProjectDirs(appName string) map[string]ProjectDirConfig {
+	dotted := "." + appName
+	xdg := filepath.Join(".config", appName)
+
+	return map[string]ProjectDirConfig{
+		"config": {Patterns: []string{xdg, dotted}},
+		"cache":  {Patterns: []string{filepath.Join(dotted, "cache")}},
+		"data":   {Patterns: []string{filepath.Join(dotted, "data")}},
+		"state":  {Patterns: []string{filepath.Join(dotted, "state")}},
+		"log":    {Patterns: []string{filepath.Join(dotted, "log")}},
+	}
+}
+
+// ProjectDir returns the primary and alternate paths for a project directory type.
+// The primary is the first existing directory; alternates are other existing locations.
+// For writes, use DefaultProjectDir which returns the first pattern regardless of existence.
+func (c *Cascade) ProjectDir(name string) (string, []string, error) {
+	candidates, err := c.projectDirCandidates(name)
+	if err != nil {
+		return "", nil, err
+	}
+	primary, alternates := firstAndRest(candidates, c.isDir)
+	return primary, alternates, nil
+}
+
+// ProjectConfigDir returns the existing project config directories.
+func (c *Cascade) ProjectConfigDir() (string, []string, error) {
+	return c.ProjectDir("config")
+}
+
+// ProjectCacheDir returns the existing project cache directories.
+func (c *Cascade) ProjectCacheDir() (string, []string, error) {
+	return c.ProjectDir("cache")
+}
+
+// ProjectDataDir returns the existing project data directories.
+func (c *Cascade) ProjectDataDir() (string, []string, error) {
+	return c.ProjectDir("data")
+}
+
+// ProjectStateDir returns the existing project state directories.
+func (c *Cascade) ProjectStateDir() (string, []string, error) {
+	return c.ProjectDir("state")
+}
+
+// ProjectLogDir returns the existing project log directories.
+func (c *Cascade) ProjectLogDir() (string, []string, error) {
+	return c.ProjectDir("log")
+}
+
+// DefaultProjectDir returns the default path for a project directory type.
+// This is the first pattern, used for writes. Does not check existence.
+func (c *Cascade) DefaultProjectDir(name string) (string, error) {
+	candidates, err := c.projectDirCandidates(name)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0], nil
+}
+
+// JoinProjectDir joins path elements to a project directory type's default path.
+func (c *Cascade) JoinProjectDir(dirType string, elem ...string) (string, error) {
+	dir, err := c.DefaultProjectDir(dirType)
+	if err != nil {
+		return "", err
+	}
+	return path(dir, elem...), nil
+}
+
+// ProjectSubdir returns paths for a named subdirectory within a project directory type.
+// Every pattern of the directory type is combined with every variant of the
+// subdirectory; the first existing one is primary.
+func (c *Cascade) ProjectSubdir(dirType, subdirName string) (string, []string, error) {
+	candidates, err := c.projectSubdirCandidates(dirType, subdirName)
+	if err != nil {
+		return "", nil, err
+	}
+	primary, alternates := firstAndRest(candidates, c.isDir)
+	return primary, alternates, nil
+}
+
+// DefaultProjectSubdir returns the default path for a subdirectory.
+func (c *Cascade) DefaultProjectSubdir(dirType, subdirName string) (string, error) {
+	candidates, err := c.projectSubdirCandidates(dirType, subdirName)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0], nil
+}
+
+// ResolveProjectFile finds a file within a project directory type.
+func (c *Cascade) ResolveProjectFile(dirType, basename string) (string, []string, error) {
+	dirs, err := c.projectDirCandidates(dirType)
+	if err != nil {
+		return "", nil, err
+	}
+	primary, alternates := firstAndRest(crossJoin(dirs, c.candidates(basename)), c.exists)
+	return primary, alternates, nil
+}
+
+// ResolveProjectFileIn finds a file within a specific subdirectory of a project directory type.
+func (c *Cascade) ResolveProjectFileIn(dirType, subdir, basename string) (string, []string, error) {
+	dirs, err := c.projectSubdirCandidates(dirType, subdir)
+	if err != nil {
+		return "", nil, err
+	}
+	primary, alternates := firstAndRest(crossJoin(dirs, c.candidates(basename)), c.exists)
+	return primary, alternates, nil
+}
+
+// DefaultProjectFile returns the default path for a file in a project directory type.
+func (c *Cascade) DefaultProjectFile(dirType, basename string) (string, error) {
+	return c.JoinProjectDir(dirType, c.defaultName(basename))
+}
+
+// projectDirConfig looks up a configured project directory type.
+func (c *Cascade) projectDirConfig(name string) (ProjectDirConfig, error) {
+	cfg, ok := c.cfg.ProjectDirs[name]
+	if !ok || len(cfg.Patterns) == 0 {
+		return ProjectDirConfig{}, fmt.Errorf("%w: %s", ErrProjectDirNotFound, name)
+	}
+	return cfg, nil
+}
+
+// projectDirCandidates returns every pattern of a directory type joined to
+// the project root, in order.
+func (c *Cascade) projectDirCandidates(name string) ([]string, error) {
+	cfg, err := c.projectDirConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	root, err := c.requireProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(cfg.Patterns))
+	for _, pattern := range c.expandAll(cfg.Patterns) {
+		dirs = append(dirs, filepath.Join(root, pattern))
+	}
+	return dirs, nil
+}
+
+// projectSubdirCandidates returns every variant of a named subdirectory
+// under every pattern of its directory type, pattern-major.
+func (c *Cascade) projectSubdirCandidates(dirType, subdirName string) ([]string, error) {
+	cfg, err := c.projectDirConfig(dirType)
+	if err != nil {
+		return nil, err
+	}
+	variants := cfg.Subdirs[subdirName]
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s", ErrProjectDirNotFound, dirType, subdirName)
+	}
+	dirs, err := c.projectDirCandidates(dirType)
+	if err != nil {
+		return nil, err
+	}
+	return crossJoin(dirs, c.expandAll(variants)), nil
+}
+
+// crossJoin joins each name onto each directory, directory-major.
+func crossJoin(dirs, names []string) []string {
+	paths := make([]string, 0, len(dirs)*len(names))
+	for _, dir := range dirs {
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths
+}
+
+// ---------------------------------------------------------------------
+// Known project files
+// ---------------------------------------------------------------------
+
+// ProjectFileConfig defines a known file for convenient resolution.
+type ProjectFileConfig struct {
+	// Variants are filename variants to check, in order.
+	// Supports {app} placeholder for the app name.
+	Variants []string
+
+	// Dir is which project directory type this file lives in.
+	// Empty string means project root directly.
+	Dir string
+
+	// Subdirs to also check within Dir (in addition to Dir root).
+	// Useful for files that might live in either .myapp/config.yaml
+	// or .myapp/conf.d/config.yaml.
+	Subdirs []string
+}
+
+// ResolveKnownFile resolves a file by its configured name (from ProjectFiles).
+func (c *Cascade) ResolveKnownFile(name string) (string, []string, error) {
+	candidates, err := c.knownFileCandidates(name)
+	if err != nil {
+		return "", nil, err
+	}
+	primary, alternates := firstAndRest(candidates, c.exists)
+	return primary, alternates, nil
+}
+
+// DefaultKnownFile returns the default path for a known file: the first
+// variant in the first location.
+func (c *Cascade) DefaultKnownFile(name string) (string, error) {
+	candidates, err := c.knownFileCandidates(name)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0], nil
+}
+
+// knownFileCandidates returns every variant of a known file in every
+// location it may live, location-major.
+func (c *Cascade) knownFileCandidates(name string) ([]string, error) {
+	cfg, ok := c.cfg.ProjectFiles[name]
+	if !ok || len(cfg.Variants) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrProjectFileNotFound, name)
+	}
+
+	var bases []string
+	if cfg.Dir == "" {
+		root, err := c.requireProjectRoot()
+		if err != nil {
+			return nil, err
+		}
+		bases = []string{root}
+	} else {
+		dirs, err := c.projectDirCandidates(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		bases = dirs
+	}
+
+	var dirs []string
+	for _, base := range bases {
+		dirs = append(dirs, base)
+		for _, sub := range c.expandAll(cfg.Subdirs) {
+			dirs = append(dirs, filepath.Join(base, sub))
+		}
+	}
+	return crossJoin(dirs, c.expandAll(cfg.Variants)), nil
+}
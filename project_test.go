@@ -0,0 +1,240 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestCascadeProjectRootConfigDirAware(t *testing.T) {
+	base := testBase()
+	repo := p(base, "repo")
+
+	tests := []struct {
+		name string
+		cwd  string
+		want string
+	}{
+		{"inside dotted app dir", p(repo, ".myapp", "hooks"), repo},
+		{"at dotted app dir", p(repo, ".myapp"), repo},
+		{"inside xdg-style config dir", p(base, "home", ".config", "myapp", "policies.d"), p(base, "home")},
+		{"nested project app dir wins", p(repo, "sub", ".myapp", "hooks"), p(repo, "sub")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestCascade(t, base, tt.cwd)
+			// A parent repository that plain traversal would otherwise find.
+			fake.SetExisting(p(base, ".git"))
+
+			root, err := c.ProjectRoot()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, root)
+		})
+	}
+}
+
+func TestCascadeProjectRootIgnoresSimilarNames(t *testing.T) {
+	base := testBase()
+	c, fake := newTestCascade(t, base, p(base, "repo", ".myapp-cache", "x"))
+	fake.SetExisting(p(base, ".git"))
+
+	root, err := c.ProjectRoot()
+	require.NoError(t, err)
+	assert.Equal(t, base, root)
+}
+
+func TestCascadeProjectRootCustomConfigDirPatterns(t *testing.T) {
+	base := testBase()
+	repo := p(base, "repo")
+	c, fake := newTestCascade(t, base, p(repo, "tools", "myapp", "conf"),
+		toolpaths.WithConfigDirPatterns("tools/{app}"))
+	fake.SetExisting(p(base, ".git"))
+
+	root, err := c.ProjectRoot()
+	require.NoError(t, err)
+	assert.Equal(t, repo, root)
+}
+
+func TestCascadeProjectRootCached(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	cwd := p(project, "src")
+
+	calls := 0
+	c, fake := newTestCascade(t, base, cwd, toolpaths.WithGetwd(func() (string, error) {
+		calls++
+		return cwd, nil
+	}))
+	fake.SetExisting(p(project, ".git"))
+
+	root, err := c.ProjectRoot()
+	require.NoError(t, err)
+	assert.Equal(t, project, root)
+
+	// Removing the marker does not change the cached answer.
+	fake.SetNotExisting(p(project, ".git"))
+	root, err = c.ProjectRoot()
+	require.NoError(t, err)
+	assert.Equal(t, project, root)
+	assert.Equal(t, 2, calls, "working directory is consulted on each call")
+
+	c.ClearProjectRootCache()
+	root, err = c.ProjectRoot()
+	require.NoError(t, err)
+	assert.Empty(t, root)
+}
+
+func TestCascadeProjectRootCachedPerWorkingDirectory(t *testing.T) {
+	base := testBase()
+	first := p(base, "one")
+	second := p(base, "two")
+
+	cwd := first
+	c, fake := newTestCascade(t, base, cwd, toolpaths.WithGetwd(func() (string, error) {
+		return cwd, nil
+	}))
+	fake.SetExisting(p(first, ".git"))
+	fake.SetExisting(p(second, ".hg"))
+
+	root, err := c.ProjectRoot()
+	require.NoError(t, err)
+	assert.Equal(t, first, root)
+
+	cwd = second
+	root, err = c.ProjectRoot()
+	require.NoError(t, err)
+	assert.Equal(t, second, root)
+}
+
+func TestCascadeDefaultProjectDirs(t *testing.T) {
+	dirs := toolpaths.DefaultProjectDirs("myapp")
+	assert.Equal(t, []string{p(".config", "myapp"), ".myapp"}, dirs["config"].Patterns)
+	assert.Equal(t, []string{p(".myapp", "cache")}, dirs["cache"].Patterns)
+	assert.Equal(t, []string{p(".myapp", "data")}, dirs["data"].Patterns)
+	assert.Equal(t, []string{p(".myapp", "state")}, dirs["state"].Patterns)
+	assert.Equal(t, []string{p(".myapp", "log")}, dirs["log"].Patterns)
+}
+
+func TestCascadeProjectDir(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	projectDirs := toolpaths.WithProjectDirs(map[string]toolpaths.ProjectDirConfig{
+		"cache": {Patterns: []string{".{app}/cache", ".{app}-cache"}},
+		"config": {
+			Patterns: []string{".config/{app}", ".{app}"},
+			Subdirs: map[string][]string{
+				"policies": {"policies.d", "policies"},
+			},
+		},
+	})
+
+	t.Run("first existing pattern is primary", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, projectDirs)
+		fake.SetExisting(p(project, ".git"))
+		fake.SetExisting(p(project, ".myapp-cache"))
+
+		primary, alternates, err := c.ProjectCacheDir()
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".myapp-cache"), primary)
+		assert.Empty(t, alternates)
+	})
+
+	t.Run("existing alternates", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, projectDirs)
+		fake.SetExisting(p(project, ".git"))
+		fake.SetExisting(p(project, ".myapp", "cache"))
+		fake.SetExisting(p(project, ".myapp-cache"))
+
+		primary, alternates, err := c.ProjectDir("cache")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".myapp", "cache"), primary)
+		assert.Equal(t, []string{p(project, ".myapp-cache")}, alternates)
+	})
+
+	t.Run("none existing", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, projectDirs)
+		fake.SetExisting(p(project, ".git"))
+
+		primary, alternates, err := c.ProjectDir("cache")
+		require.NoError(t, err)
+		assert.Empty(t, primary)
+		assert.Nil(t, alternates)
+	})
+
+	t.Run("default is first pattern regardless of existence", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, projectDirs)
+		fake.SetExisting(p(project, ".git"))
+		fake.SetExisting(p(project, ".myapp-cache"))
+
+		dir, err := c.DefaultProjectDir("cache")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".myapp", "cache"), dir)
+
+		joined, err := c.JoinProjectDir("cache", "http", "index")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".myapp", "cache", "http", "index"), joined)
+	})
+
+	t.Run("named subdirectories", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, projectDirs)
+		fake.SetExisting(p(project, ".git"))
+		fake.SetExisting(p(project, ".myapp", "policies.d"))
+		fake.SetExisting(p(project, ".config", "myapp", "policies"))
+
+		primary, alternates, err := c.ProjectSubdir("config", "policies")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".config", "myapp", "policies"), primary)
+		assert.Equal(t, []string{p(project, ".myapp", "policies.d")}, alternates)
+
+		dir, err := c.DefaultProjectSubdir("config", "policies")
+		require.NoError(t, err)
+		assert.Equal(t, p(project, ".config", "myapp", "policies.d"), dir)
+	})
+
+	t.Run("unknown types", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, projectDirs)
+		fake.SetExisting(p(project, ".git"))
+
+		_, _, err := c.ProjectDir("log")
+		require.ErrorIs(t, err, toolpaths.ErrProjectDirNotFound)
+		_, err = c.DefaultProjectSubdir("config", "hooks")
+		require.ErrorIs(t, err, toolpaths.ErrProjectDirNotFound)
+	})
+
+	t.Run("outside a project", func(t *testing.T) {
+		c, _ := newTestCascade(t, base, project, projectDirs)
+
+		_, _, err := c.ProjectDir("cache")
+		require.ErrorIs(t, err, toolpaths.ErrNoProjectRoot)
+	})
+}
+
+func TestCascadeResolveProjectFile(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	c, fake := newTestCascade(t, base, project, toolpaths.WithProjectDirs(map[string]toolpaths.ProjectDirConfig{
+		"config": {
+			Patterns: []string{".config/{app}", ".{app}"},
+			Subdirs:  map[string][]string{"conf": {"conf.d"}},
+		},
+	}))
+	fake.SetExisting(p(project, ".git"))
+	fake.SetExisting(p(project, ".myapp", "settings.json"))
+	fake.SetExisting(p(project, ".myapp", "conf.d", "extra.json"))
+
+	primary, alternates, err := c.ResolveProjectFile("config", "settings.json")
+	require.NoError(t, err)
+	assert.Equal(t, p(project, ".myapp", "settings.json"), primary)
+	assert.Empty(t, alternates)
+
+	primary, _, err = c.ResolveProjectFileIn("config", "conf", "extra.json")
+	require.NoError(t, err)
+	assert.Equal(t, p(project, ".myapp", "conf.d", "extra.json"), primary)
+
+	def, err := c.DefaultProjectFile("config", "settings.json")
+	require.NoError(t, err)
+	assert.Equal(t, p(project, ".config", "myapp", "settings.json"), def)
+}
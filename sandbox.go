@@ -0,0 +1,297 @@
+package toolpaths
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+)
+
+// SandboxKind identifies an application sandbox.
+type SandboxKind int
+
+const (
+	// SandboxNone means no sandbox was detected. This is the zero value.
+	SandboxNone SandboxKind = iota
+	SandboxFlatpak
+	SandboxSnap
+)
+
+func (k SandboxKind) String() string {
+	switch k {
+	case SandboxNone:
+		return "none"
+	case SandboxFlatpak:
+		return "flatpak"
+	case SandboxSnap:
+		return "snap"
+	default:
+		return "unknown"
+	}
+}
+
+// flatpakInfoPath is the file Flatpak mounts into every sandbox.
+const flatpakInfoPath = "/.flatpak-info"
+
+// Sandbox variables reported by DiagnoseEnv.
+var (
+	flatpakEnvNames = []string{
+		"FLATPAK_ID",
+		"HOST_XDG_CONFIG_HOME",
+		"HOST_XDG_DATA_HOME",
+		"HOST_XDG_CACHE_HOME",
+		"HOST_XDG_STATE_HOME",
+	}
+	snapEnvNames = []string{
+		"SNAP_NAME",
+		"SNAP_INSTANCE_NAME",
+		"SNAP",
+		"SNAP_DATA",
+		"SNAP_COMMON",
+		"SNAP_USER_DATA",
+		"SNAP_USER_COMMON",
+		"SNAP_REAL_HOME",
+	}
+)
+
+// Sandbox describes the application sandbox the process runs in.
+type Sandbox struct {
+	Kind SandboxKind
+
+	// ID is the Flatpak application ID (FLATPAK_ID) or the snap instance
+	// name (SNAP_INSTANCE_NAME, or SNAP_NAME). Empty outside a sandbox.
+	ID string
+
+	// HostHome is the user's home directory outside the sandbox. Snap
+	// remaps HOME to $SNAP_USER_DATA and reports the real one in
+	// SNAP_REAL_HOME; Flatpak leaves HOME unchanged.
+	HostHome string
+}
+
+// HostPath pairs a user directory inside the sandbox with the location an
+// unsandboxed install of the same app uses on the host, for migration.
+type HostPath struct {
+	Kind    string // Directory kind, as in DirInfo.Kind
+	Sandbox string // Directory used inside the sandbox
+	Host    string // Host-side XDG location
+}
+
+// Sandbox returns the sandbox detected when the PlatformDirs was created.
+// Flatpak is detected from FLATPAK_ID or /.flatpak-info, Snap from
+// SNAP_NAME. Detection only runs on Linux and is skipped if
+// Config.IgnoreSandbox is set.
+//
+// Inside a sandbox, directories follow its conventions:
+//
+//   - Flatpak: user directories use the XDG variables Flatpak sets, or
+//     ~/.var/app/{id}/{config,data,cache,.local/state} when they are unset;
+//     the runtime directory is $XDG_RUNTIME_DIR/app/{id}, which is shared
+//     with the host; system config and data default to /app/etc/xdg and
+//     /app/share ahead of the host-provided /etc/xdg and /usr/share.
+//   - Snap: config and data live in $SNAP_USER_DATA, which is versioned
+//     per revision; cache and state live in $SNAP_USER_COMMON so they
+//     survive refreshes. System config and data use $SNAP_DATA ahead of
+//     the read-only $SNAP; cache, state, and logs use $SNAP_COMMON; the
+//     runtime directory is /run/snap.{name}.
+//
+// EnvOverrides and Config.Systemd still take precedence.
+func (d *PlatformDirs) Sandbox() Sandbox {
+	return d.sandbox
+}
+
+// SandboxHostPaths returns the user directories inside the sandbox paired
+// with their host-side locations, so data written by an unsandboxed
+// install can be migrated. Returns nil outside a sandbox. Reading the
+// host paths may require a filesystem permission in the sandbox manifest.
+func (d *PlatformDirs) SandboxHostPaths() []HostPath {
+	if d.sandbox.Kind == SandboxNone {
+		return nil
+	}
+	var paths []HostPath
+	for _, dt := range []dirType{userConfig, userData, userCache, userState, userLog} {
+		paths = append(paths, HostPath{
+			Kind:    dt.String(),
+			Sandbox: d.resolveUserDirForFallbacks(dt),
+			Host:    d.sandboxHostDir(dt),
+		})
+	}
+	return paths
+}
+
+// ---------------------------------------------------------------------
+// Internal: detection
+// ---------------------------------------------------------------------
+
+// detectSandbox inspects the environment and, for Flatpak, the info file.
+func (d *PlatformDirs) detectSandbox() Sandbox {
+	if d.cfg.IgnoreSandbox || d.platform != PlatformLinux {
+		return Sandbox{}
+	}
+
+	if id := d.getenv("FLATPAK_ID"); id != "" {
+		return Sandbox{Kind: SandboxFlatpak, ID: id, HostHome: d.homeDir()}
+	}
+	if fsExists(d.fsys, flatpakInfoPath) {
+		return Sandbox{Kind: SandboxFlatpak, ID: flatpakInfoName(d.fsys), HostHome: d.homeDir()}
+	}
+
+	if name := d.getenv("SNAP_NAME"); name != "" {
+		sb := Sandbox{Kind: SandboxSnap, ID: name, HostHome: d.getenv("SNAP_REAL_HOME")}
+		if instance := d.getenv("SNAP_INSTANCE_NAME"); instance != "" {
+			sb.ID = instance
+		}
+		if sb.HostHome == "" {
+			sb.HostHome = d.homeDir()
+		}
+		return sb
+	}
+
+	return Sandbox{}
+}
+
+// flatpakInfoName reads the application ID from the [Application] group
+// of /.flatpak-info.
+func flatpakInfoName(fsys FS) string {
+	f, err := fsys.Open(flatpakInfoPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var inApplication bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inApplication = line == "[Application]"
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && inApplication && strings.TrimSpace(key) == "name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// ---------------------------------------------------------------------
+// Internal: sandbox resolution
+// ---------------------------------------------------------------------
+
+// sandboxDirs returns the directories the sandbox conventions give for dt,
+// or nil if the sandbox has no convention for it (or there is no sandbox).
+// Single-directory kinds return at most one entry.
+func (d *PlatformDirs) sandboxDirs(dt dirType) []string {
+	switch d.sandbox.Kind {
+	case SandboxFlatpak:
+		return d.flatpakDirs(dt)
+	case SandboxSnap:
+		return d.snapDirs(dt)
+	default:
+		return nil
+	}
+}
+
+func (d *PlatformDirs) flatpakDirs(dt dirType) []string {
+	appData := filepath.Join(d.sandbox.HostHome, ".var", "app", d.sandbox.ID)
+
+	switch dt { //nolint:exhaustive // other kinds follow the platform
+	case userConfig, userData, userCache, userState, userLog:
+		if dir := d.xdgUserDirEnvOnly(dt); dir != "" {
+			return []string{dir}
+		}
+		if d.sandbox.ID == "" {
+			return nil
+		}
+		switch dt { //nolint:exhaustive // user dir types only
+		case userConfig:
+			return []string{filepath.Join(appData, "config", d.appPath(dt))}
+		case userData:
+			return []string{filepath.Join(appData, "data", d.appPath(dt))}
+		case userCache:
+			return []string{filepath.Join(appData, "cache", d.appPath(dt))}
+		case userState:
+			return []string{filepath.Join(appData, ".local", "state", d.appPath(dt))}
+		default:
+			return []string{filepath.Join(appData, ".local", "state", d.appPath(dt), "log")}
+		}
+	case userRuntime:
+		if runtimeDir := d.xdgEnv("XDG_RUNTIME_DIR"); runtimeDir != "" && d.sandbox.ID != "" {
+			return []string{filepath.Join(runtimeDir, "app", d.sandbox.ID, d.appPath(dt))}
+		}
+		return nil
+	case systemConfig:
+		if d.getenv("XDG_CONFIG_DIRS") != "" {
+			return nil
+		}
+		return []string{filepath.Join("/app", "etc", "xdg", d.appPath(dt)), filepath.Join("/etc", "xdg", d.appPath(dt))}
+	case systemData:
+		if d.getenv("XDG_DATA_DIRS") != "" {
+			return nil
+		}
+		return []string{filepath.Join("/app", "share", d.appPath(dt)), filepath.Join("/usr", "share", d.appPath(dt))}
+	default:
+		return nil
+	}
+}
+
+func (d *PlatformDirs) snapDirs(dt dirType) []string {
+	join := func(env string, elem ...string) []string {
+		base := d.getenv(env)
+		if base == "" {
+			return nil
+		}
+		return []string{filepath.Join(append([]string{base}, elem...)...)}
+	}
+	app := d.appPath(dt)
+
+	switch dt { //nolint:exhaustive // runtime and managed dirs follow the platform
+	case userConfig:
+		return join("SNAP_USER_DATA", ".config", app)
+	case userData:
+		return join("SNAP_USER_DATA", ".local", "share", app)
+	case userCache:
+		return join("SNAP_USER_COMMON", ".cache", app)
+	case userState:
+		return join("SNAP_USER_COMMON", ".local", "state", app)
+	case userLog:
+		return join("SNAP_USER_COMMON", ".local", "state", app, "log")
+	case systemConfig:
+		return append(join("SNAP_DATA", "etc", app), join("SNAP", "etc", app)...)
+	case systemData:
+		return append(join("SNAP_DATA", "share", app), join("SNAP", "usr", "share", app)...)
+	case systemCache:
+		return join("SNAP_COMMON", "cache", app)
+	case systemState:
+		return join("SNAP_COMMON", "lib", app)
+	case systemLog:
+		return join("SNAP_COMMON", "log", app)
+	case systemRuntime:
+		return []string{filepath.Join("/run", "snap."+d.sandbox.ID, app)}
+	default:
+		return nil
+	}
+}
+
+// sandboxHostDir returns the host-side XDG location for a user directory.
+// Flatpak passes the host's XDG variables through as HOST_XDG_*.
+func (d *PlatformDirs) sandboxHostDir(dt dirType) string {
+	var hostEnv, sub, leaf string
+	switch dt { //nolint:exhaustive // only user dir types have host paths
+	case userConfig:
+		hostEnv, sub = "HOST_XDG_CONFIG_HOME", ".config"
+	case userData:
+		hostEnv, sub = "HOST_XDG_DATA_HOME", filepath.Join(".local", "share")
+	case userCache:
+		hostEnv, sub = "HOST_XDG_CACHE_HOME", ".cache"
+	case userState:
+		hostEnv, sub = "HOST_XDG_STATE_HOME", filepath.Join(".local", "state")
+	case userLog:
+		hostEnv, sub, leaf = "HOST_XDG_STATE_HOME", filepath.Join(".local", "state"), "log"
+	default:
+		return ""
+	}
+
+	if dir := d.getenv(hostEnv); dir != "" && d.sandbox.Kind == SandboxFlatpak {
+		return filepath.Join(dir, d.appPath(dt), leaf)
+	}
+	return filepath.Join(d.sandbox.HostHome, sub, d.appPath(dt), leaf)
+}
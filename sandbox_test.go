@@ -0,0 +1,206 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// setTestSandboxEnv isolates HOME and clears the variables sandbox
+// detection and resolution read.
+func setTestSandboxEnv(t *testing.T) string {
+	t.Helper()
+	home := setTestHomeXDG(t)
+	for _, name := range []string{
+		"FLATPAK_ID", "XDG_CONFIG_DIRS", "XDG_DATA_DIRS",
+		"HOST_XDG_CONFIG_HOME", "HOST_XDG_DATA_HOME", "HOST_XDG_CACHE_HOME", "HOST_XDG_STATE_HOME",
+		"SNAP_NAME", "SNAP_INSTANCE_NAME", "SNAP", "SNAP_DATA", "SNAP_COMMON",
+		"SNAP_USER_DATA", "SNAP_USER_COMMON", "SNAP_REAL_HOME",
+	} {
+		t.Setenv(name, "")
+	}
+	return home
+}
+
+func TestSandboxNone(t *testing.T) {
+	home := setTestSandboxEnv(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformLinux,
+		FS:       toolpaths.NewMapFS(), // keep a real /.flatpak-info out of the way
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, toolpaths.Sandbox{}, dirs.Sandbox())
+	assert.Equal(t, "none", dirs.Sandbox().Kind.String())
+	assert.Nil(t, dirs.SandboxHostPaths())
+	assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir())
+}
+
+func TestSandboxFlatpak(t *testing.T) {
+	home := setTestSandboxEnv(t)
+	t.Setenv("FLATPAK_ID", "org.example.App")
+	t.Setenv("XDG_RUNTIME_DIR", p(home, "run"))
+	appData := p(home, ".var", "app", "org.example.App")
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformLinux,
+		FS:       toolpaths.NewMapFS(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, toolpaths.Sandbox{
+		Kind: toolpaths.SandboxFlatpak, ID: "org.example.App", HostHome: home,
+	}, dirs.Sandbox())
+
+	assert.Equal(t, p(appData, "config", "myapp"), dirs.UserConfigDir())
+	assert.Equal(t, p(appData, "data", "myapp"), dirs.UserDataDir())
+	assert.Equal(t, p(appData, "cache", "myapp"), dirs.UserCacheDir())
+	assert.Equal(t, p(appData, ".local", "state", "myapp"), dirs.UserStateDir())
+	assert.Equal(t, p(appData, ".local", "state", "myapp", "log"), dirs.UserLogDir())
+	rt, err := dirs.UserRuntimeDir()
+	require.NoError(t, err)
+	assert.Equal(t, p(home, "run", "app", "org.example.App", "myapp"), rt)
+	assert.Equal(t, []string{p("/app", "etc", "xdg", "myapp"), p("/etc", "xdg", "myapp")}, dirs.SystemConfigDirs())
+	assert.Equal(t, []string{p("/app", "share", "myapp"), p("/usr", "share", "myapp")}, dirs.SystemDataDirs())
+	assert.Equal(t, p("/var", "lib", "myapp"), dirs.SystemStateDir(), "no Flatpak convention")
+
+	t.Run("XDG variables set by Flatpak win", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", p(appData, "config-from-env"))
+		t.Setenv("XDG_DATA_DIRS", p("/app", "share-from-env"))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:  "myapp",
+			Platform: toolpaths.PlatformLinux,
+			FS:       toolpaths.NewMapFS(),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, p(appData, "config-from-env", "myapp"), dirs.UserConfigDir())
+		assert.Equal(t, []string{p("/app", "share-from-env", "myapp")}, dirs.SystemDataDirs())
+	})
+
+	t.Run("host paths", func(t *testing.T) {
+		t.Setenv("HOST_XDG_CONFIG_HOME", p(home, "host-config"))
+		hosts := map[string]toolpaths.HostPath{}
+		for _, hp := range dirs.SandboxHostPaths() {
+			hosts[hp.Kind] = hp
+		}
+		require.Len(t, hosts, 5)
+		assert.Equal(t, toolpaths.HostPath{
+			Kind:    "user-config",
+			Sandbox: p(appData, "config", "myapp"),
+			Host:    p(home, "host-config", "myapp"),
+		}, hosts["user-config"])
+		assert.Equal(t, p(home, ".local", "state", "myapp", "log"), hosts["user-log"].Host)
+	})
+
+	t.Run("explain", func(t *testing.T) {
+		x, err := dirs.Explain("user-cache")
+		require.NoError(t, err)
+		step, ok := x.Chosen()
+		require.True(t, ok)
+		assert.Equal(t, toolpaths.StrategySandbox, step.Strategy)
+		assert.Equal(t, x.Path, step.Path)
+	})
+
+	t.Run("diagnose", func(t *testing.T) {
+		diag := dirs.Diagnose()
+		assert.Equal(t, toolpaths.SandboxFlatpak, diag.Sandbox.Kind)
+		assert.Contains(t, diag.String(), `Sandbox:   flatpak "org.example.App"`)
+	})
+}
+
+func TestSandboxFlatpakInfoFile(t *testing.T) {
+	home := setTestSandboxEnv(t)
+	mem := toolpaths.NewMapFS()
+	require.NoError(t, mem.WriteFile("/.flatpak-info",
+		[]byte("[Instance]\nname=wrong\n\n[Application]\nname = org.example.Info\nruntime=runtime/x\n"), 0o644))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformLinux, FS: mem})
+	require.NoError(t, err)
+	assert.Equal(t, toolpaths.SandboxFlatpak, dirs.Sandbox().Kind)
+	assert.Equal(t, "org.example.Info", dirs.Sandbox().ID)
+	assert.Equal(t, p(home, ".var", "app", "org.example.Info", "config", "myapp"), dirs.UserConfigDir())
+}
+
+func TestSandboxSnap(t *testing.T) {
+	home := setTestSandboxEnv(t)
+	realHome := p(home, "real")
+	userData := p(realHome, "snap", "mytool", "42")
+	userCommon := p(realHome, "snap", "mytool", "common")
+	t.Setenv("SNAP_NAME", "mytool")
+	t.Setenv("SNAP_INSTANCE_NAME", "mytool_beta")
+	t.Setenv("SNAP", p("/snap", "mytool", "42"))
+	t.Setenv("SNAP_DATA", p("/var", "snap", "mytool", "42"))
+	t.Setenv("SNAP_COMMON", p("/var", "snap", "mytool", "common"))
+	t.Setenv("SNAP_USER_DATA", userData)
+	t.Setenv("SNAP_USER_COMMON", userCommon)
+	t.Setenv("SNAP_REAL_HOME", realHome)
+	t.Setenv("XDG_CONFIG_HOME", p(realHome, ".config")) // leaked from the host
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformLinux,
+		FS:       toolpaths.NewMapFS(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, toolpaths.Sandbox{
+		Kind: toolpaths.SandboxSnap, ID: "mytool_beta", HostHome: realHome,
+	}, dirs.Sandbox())
+
+	assert.Equal(t, p(userData, ".config", "myapp"), dirs.UserConfigDir())
+	assert.Equal(t, p(userData, ".local", "share", "myapp"), dirs.UserDataDir())
+	assert.Equal(t, p(userCommon, ".cache", "myapp"), dirs.UserCacheDir())
+	assert.Equal(t, p(userCommon, ".local", "state", "myapp"), dirs.UserStateDir())
+	assert.Equal(t, p(userCommon, ".local", "state", "myapp", "log"), dirs.UserLogDir())
+
+	assert.Equal(t, []string{
+		p("/var", "snap", "mytool", "42", "etc", "myapp"),
+		p("/snap", "mytool", "42", "etc", "myapp"),
+	}, dirs.SystemConfigDirs())
+	assert.Equal(t, p("/var", "snap", "mytool", "common", "lib", "myapp"), dirs.SystemStateDir())
+	assert.Equal(t, p("/var", "snap", "mytool", "common", "cache", "myapp"), dirs.SystemCacheDir())
+	assert.Equal(t, p("/var", "snap", "mytool", "common", "log", "myapp"), dirs.SystemLogDir())
+	assert.Equal(t, p("/run", "snap.mytool_beta", "myapp"), dirs.SystemRuntimeDir())
+
+	hosts := dirs.SandboxHostPaths()
+	require.NotEmpty(t, hosts)
+	assert.Equal(t, p(realHome, ".config", "myapp"), hosts[0].Host)
+
+	t.Run("EnvOverrides win", func(t *testing.T) {
+		t.Setenv("MYAPP_STATE", p(home, "override"))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:      "myapp",
+			Platform:     toolpaths.PlatformLinux,
+			FS:           toolpaths.NewMapFS(),
+			EnvOverrides: &toolpaths.EnvOverrides{UserState: "MYAPP_STATE"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, p(home, "override"), dirs.UserStateDir())
+	})
+}
+
+func TestSandboxIgnored(t *testing.T) {
+	home := setTestSandboxEnv(t)
+	t.Setenv("FLATPAK_ID", "org.example.App")
+
+	ignored, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:       "myapp",
+		Platform:      toolpaths.PlatformLinux,
+		FS:            toolpaths.NewMapFS(),
+		IgnoreSandbox: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, toolpaths.SandboxNone, ignored.Sandbox().Kind)
+	assert.Equal(t, p(home, ".config", "myapp"), ignored.UserConfigDir())
+
+	macOS, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformMacOS,
+		FS:       toolpaths.NewMapFS(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, toolpaths.SandboxNone, macOS.Sandbox().Kind, "detection is Linux-only")
+}
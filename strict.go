@@ -0,0 +1,120 @@
+package toolpaths
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ErrXDGViolation is wrapped by every XDGViolation.
+var ErrXDGViolation = errors.New("toolpaths: XDG base directory violation")
+
+// XDGViolation describes an environment value that breaks the XDG Base
+// Directory specification, found while Config.StrictXDG is set.
+type XDGViolation struct {
+	EnvVar string // Variable holding the offending value
+	Value  string // The offending value or search path entry
+	Reason string // What the specification requires
+
+	// Fatal is true when resolution fails because of the violation, as
+	// for an insecure XDG_RUNTIME_DIR. Otherwise the value was skipped and
+	// resolution continued, and the violation is a warning.
+	Fatal bool
+}
+
+// Error implements error. Violations wrap ErrXDGViolation.
+func (v *XDGViolation) Error() string {
+	return fmt.Sprintf("toolpaths: %s=%q: %s", v.EnvVar, v.Value, v.Reason)
+}
+
+// Unwrap returns ErrXDGViolation.
+func (v *XDGViolation) Unwrap() error {
+	return ErrXDGViolation
+}
+
+// ---------------------------------------------------------------------
+// Internal: strict XDG helpers
+// ---------------------------------------------------------------------
+
+// reportXDG passes a violation to Config.OnXDGViolation.
+func (d *PlatformDirs) reportXDG(v *XDGViolation) {
+	if d.cfg.OnXDGViolation != nil {
+		d.cfg.OnXDGViolation(v)
+	}
+}
+
+// xdgEnv returns an XDG base directory variable. The specification says
+// relative values are invalid, so strict mode reports and ignores them.
+func (d *PlatformDirs) xdgEnv(name string) string {
+	value := d.getenv(name)
+	if d.cfg.StrictXDG && value != "" && !filepath.IsAbs(value) {
+		d.reportXDG(&XDGViolation{EnvVar: name, Value: value, Reason: "relative path ignored; paths must be absolute"})
+		return ""
+	}
+	return value
+}
+
+// xdgEnvList splits an XDG search path variable, or defaultVal if it is
+// unset. Strict mode drops relative entries and repeated entries,
+// reporting each one.
+func (d *PlatformDirs) xdgEnvList(name, defaultVal string) []string {
+	value := d.getenv(name)
+	if value == "" {
+		value = defaultVal
+	}
+
+	var entries []string
+	for _, entry := range strings.Split(value, ":") {
+		switch {
+		case entry == "":
+			continue
+		case !d.cfg.StrictXDG:
+		case !filepath.IsAbs(entry):
+			d.reportXDG(&XDGViolation{EnvVar: name, Value: entry, Reason: "relative entry ignored; paths must be absolute"})
+			continue
+		case slices.Contains(entries, filepath.Clean(entry)):
+			d.reportXDG(&XDGViolation{EnvVar: name, Value: entry, Reason: "duplicate entry ignored"})
+			continue
+		default:
+			entry = filepath.Clean(entry)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// checkRuntimeDir enforces the specification's requirements for
+// XDG_RUNTIME_DIR in strict mode: it must exist, be owned by the user, and
+// have mode 0700. Ownership is checked where the FS reports it, and the
+// mode is not checked on Windows.
+func (d *PlatformDirs) checkRuntimeDir(dir string) error {
+	if !d.cfg.StrictXDG {
+		return nil
+	}
+	fail := func(reason string) error {
+		v := &XDGViolation{EnvVar: "XDG_RUNTIME_DIR", Value: dir, Reason: reason, Fatal: true}
+		d.reportXDG(v)
+		return v
+	}
+
+	fi, err := d.fsys.Stat(dir)
+	if err != nil {
+		return fail("runtime directory does not exist")
+	}
+	if !fi.IsDir() {
+		return fail("runtime directory is not a directory")
+	}
+	if _, uid := fileOwner(fi); uid >= 0 && uid != d.uid() {
+		return fail(fmt.Sprintf("owned by uid %d, not the current user (uid %d)", uid, d.uid()))
+	}
+	if _, isOS := d.fsys.(osFS); isOS && runtime.GOOS == osWindows {
+		return nil
+	}
+	if perm := fi.Mode().Perm(); perm != 0o700 {
+		return fail(fmt.Sprintf("mode %04o, want 0700", perm))
+	}
+	return nil
+}
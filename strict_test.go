@@ -0,0 +1,128 @@
+package toolpaths_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// newStrictDirs returns strict Linux dirs over env and mem, collecting
+// every reported violation.
+func newStrictDirs(
+	t *testing.T, env map[string]string, mem *toolpaths.MapFS,
+) (*toolpaths.PlatformDirs, *[]*toolpaths.XDGViolation) {
+	t.Helper()
+	var violations []*toolpaths.XDGViolation
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:        "myapp",
+		Platform:       toolpaths.PlatformLinux,
+		HomeDir:        p(testBase(), "home"),
+		LookupEnv:      mapEnv(env),
+		UID:            func() int { return 1000 },
+		FS:             mem,
+		StrictXDG:      true,
+		OnXDGViolation: func(v *toolpaths.XDGViolation) { violations = append(violations, v) },
+	})
+	require.NoError(t, err)
+	return dirs, &violations
+}
+
+func TestStrictXDGRelativePaths(t *testing.T) {
+	home := p(testBase(), "home")
+	dirs, violations := newStrictDirs(t, map[string]string{
+		"XDG_CONFIG_HOME": "relative/config",
+		"XDG_CACHE_HOME":  p(home, "cache"),
+	}, toolpaths.NewMapFS())
+
+	assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir())
+	assert.Equal(t, p(home, "cache", "myapp"), dirs.UserCacheDir())
+	require.Len(t, *violations, 1)
+	v := (*violations)[0]
+	assert.Equal(t, "XDG_CONFIG_HOME", v.EnvVar)
+	assert.Equal(t, "relative/config", v.Value)
+	assert.False(t, v.Fatal)
+	require.ErrorIs(t, v, toolpaths.ErrXDGViolation)
+
+	x, err := dirs.Explain("user-config")
+	require.NoError(t, err)
+	step, ok := x.Chosen()
+	require.True(t, ok)
+	assert.Equal(t, toolpaths.StrategyXDGDefault, step.Strategy)
+	assert.Equal(t, x.Path, step.Path)
+}
+
+func TestStrictXDGSearchPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("XDG search paths use ':' separators, which clash with Windows drive letters")
+	}
+	dirs, violations := newStrictDirs(t, map[string]string{
+		"XDG_CONFIG_DIRS": "/etc/xdg:relative:/etc/xdg/:/opt/xdg",
+		"XDG_DATA_DIRS":   "/usr/share:/usr/share",
+	}, toolpaths.NewMapFS())
+
+	assert.Equal(t, []string{"/etc/xdg/myapp", "/opt/xdg/myapp"}, dirs.SystemConfigDirs())
+	assert.Equal(t, []string{"/usr/share/myapp"}, dirs.SystemDataDirs())
+	var reasons []string
+	for _, v := range *violations {
+		reasons = append(reasons, v.EnvVar+" "+v.Value)
+	}
+	assert.Equal(t, []string{
+		"XDG_CONFIG_DIRS relative",
+		"XDG_CONFIG_DIRS /etc/xdg/",
+		"XDG_DATA_DIRS /usr/share",
+	}, reasons)
+
+	t.Run("lenient by default", func(t *testing.T) {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:   "myapp",
+			Platform:  toolpaths.PlatformLinux,
+			LookupEnv: mapEnv(map[string]string{"XDG_DATA_DIRS": "/usr/share:/usr/share"}),
+		})
+		require.NoError(t, err)
+		assert.Len(t, dirs.SystemDataDirs(), 2)
+	})
+}
+
+func TestStrictXDGRuntimeDir(t *testing.T) {
+	runDir := p(testBase(), "run", "user", "1000")
+
+	t.Run("secure", func(t *testing.T) {
+		mem := toolpaths.NewMapFS()
+		require.NoError(t, mem.MkdirAll(runDir, 0o700))
+		dirs, violations := newStrictDirs(t, map[string]string{"XDG_RUNTIME_DIR": runDir}, mem)
+		rt, err := dirs.UserRuntimeDir()
+		require.NoError(t, err)
+		assert.Equal(t, p(runDir, "myapp"), rt)
+		assert.Empty(t, *violations)
+	})
+
+	t.Run("wrong mode", func(t *testing.T) {
+		mem := toolpaths.NewMapFS()
+		require.NoError(t, mem.MkdirAll(runDir, 0o755))
+		dirs, violations := newStrictDirs(t, map[string]string{"XDG_RUNTIME_DIR": runDir}, mem)
+		_, err := dirs.UserRuntimeDir()
+		require.ErrorIs(t, err, toolpaths.ErrXDGViolation)
+		assert.Contains(t, err.Error(), "mode 0755, want 0700")
+		require.Len(t, *violations, 1)
+		assert.True(t, (*violations)[0].Fatal)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		dirs, _ := newStrictDirs(t, map[string]string{"XDG_RUNTIME_DIR": runDir}, toolpaths.NewMapFS())
+		_, err := dirs.UserRuntimeDir()
+		require.ErrorIs(t, err, toolpaths.ErrXDGViolation)
+	})
+
+	t.Run("unset warns and falls back", func(t *testing.T) {
+		dirs, violations := newStrictDirs(t, map[string]string{}, toolpaths.NewMapFS())
+		_, err := dirs.UserRuntimeDir()
+		require.NoError(t, err)
+		require.Len(t, *violations, 1)
+		assert.Equal(t, "XDG_RUNTIME_DIR", (*violations)[0].EnvVar)
+		assert.False(t, (*violations)[0].Fatal)
+	})
+}
@@ -0,0 +1,156 @@
+package toolpaths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Invoker is the user who ran the process through sudo or doas.
+type Invoker struct {
+	Via  string // "sudo" or "doas"
+	Name string // Login name
+	UID  int
+	GID  int
+	Home string // Home directory from os/user
+}
+
+// ErrChownSymlink is returned by ChownToInvoker when a path it would change
+// is a symlink.
+var ErrChownSymlink = errors.New("toolpaths: refusing to change owner through a symlink")
+
+// ErrChownOutsideHome is returned by ChownToInvoker for a path outside the
+// invoker's home directory.
+var ErrChownOutsideHome = errors.New("toolpaths: refusing to change owner outside the invoker's home")
+
+// Elevation variables reported by DiagnoseEnv when Config.SudoAware is set.
+var sudoEnvNames = []string{"SUDO_USER", "SUDO_UID", "SUDO_GID", "DOAS_USER"}
+
+// Invoker returns the user who ran the process through sudo or doas, if
+// Config.SudoAware is set and one was detected. The user directories then
+// resolve for that user: their home directory replaces HOME unless
+// Config.HomeDir is set, their UID replaces the root UID, and on Linux the runtime directory defaults to
+// /run/user/{uid}/{app} when XDG_RUNTIME_DIR is unset.
+func (d *PlatformDirs) Invoker() (Invoker, bool) {
+	return d.invoker, d.invoker.Name != ""
+}
+
+// ChownToInvoker gives dir back to the invoking user after it was created
+// as root, typically by an EnsureUser*Dir method. Every root-owned
+// directory from the invoker's home (exclusive) down to dir is changed, so
+// parents that MkdirAll created are covered too. Directories that already
+// belong to someone else, such as the invoker's own ~/.config, are left
+// alone. A dir outside the home fails with ErrChownOutsideHome, since
+// root-owned directories there are not the invoker's to take.
+//
+// Symlinks are never followed: the invoker controls their home, so a
+// symlink anywhere below it, or a dir that is itself a symlink, fails with
+// ErrChownSymlink before anything it points to can change owners. It is a
+// no-op without an invoker, or when the FS cannot change owners (such as
+// MapFS).
+//
+// Example:
+//
+//	dir, err := dirs.EnsureUserConfigDir()
+//	if err == nil {
+//		err = dirs.ChownToInvoker(dir)
+//	}
+func (d *PlatformDirs) ChownToInvoker(dir string) error {
+	inv, ok := d.Invoker()
+	if !ok {
+		return nil
+	}
+	c, ok := d.fsys.(chowner)
+	if !ok {
+		return nil
+	}
+
+	dir = filepath.Clean(dir)
+	rel, err := filepath.Rel(inv.Home, dir)
+	if err != nil || !filepath.IsAbs(dir) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %q", ErrChownOutsideHome, dir)
+	}
+	if rel == "." {
+		return nil
+	}
+	return c.chownRootDirs(inv.Home, strings.Split(rel, string(filepath.Separator)), inv.UID, inv.GID)
+}
+
+// ---------------------------------------------------------------------
+// Internal: detection
+// ---------------------------------------------------------------------
+
+// chowner is implemented by filesystems that can change ownership: the
+// real filesystem on Unix.
+type chowner interface {
+	chownRootDirs(base string, elems []string, uid, gid int) error
+}
+
+// detectInvoker looks for the user behind sudo (SUDO_USER, SUDO_UID,
+// SUDO_GID) or doas (DOAS_USER). It only applies when running as root
+// outside Windows, so a preserved SUDO_USER in a child shell is ignored.
+func (d *PlatformDirs) detectInvoker() Invoker {
+	if !d.cfg.SudoAware || d.platform == PlatformWindows || d.uid() != 0 {
+		return Invoker{}
+	}
+
+	via := "sudo"
+	name := d.getenv("SUDO_USER")
+	if name == "" {
+		via = "doas"
+		name = d.getenv("DOAS_USER")
+	}
+	if name == "" {
+		return Invoker{}
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return Invoker{}
+	}
+	inv := Invoker{Via: via, Name: name, Home: u.HomeDir, UID: -1, GID: -1}
+	if uid, err := strconv.Atoi(u.Uid); err == nil {
+		inv.UID = uid
+	}
+	if gid, err := strconv.Atoi(u.Gid); err == nil {
+		inv.GID = gid
+	}
+	// sudo reports the IDs it ran with, which win over the passwd entry.
+	if uid, err := strconv.Atoi(d.getenv("SUDO_UID")); err == nil && via == "sudo" {
+		inv.UID = uid
+	}
+	if gid, err := strconv.Atoi(d.getenv("SUDO_GID")); err == nil && via == "sudo" {
+		inv.GID = gid
+	}
+	return inv
+}
+
+// applyInvoker makes the user directories resolve for the invoker by
+// replacing the home directory and UID of the root process. An explicit
+// Config.HomeDir is kept.
+func (d *PlatformDirs) applyInvoker() {
+	inv := d.invoker
+	if d.cfg.HomeDir == "" {
+		d.cfg.HomeDir = inv.Home
+	}
+	if inv.UID >= 0 {
+		d.cfg.UID = func() int { return inv.UID }
+	}
+	if d.platform == PlatformLinux {
+		lookup := d.cfg.LookupEnv
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		runtimeDir := filepath.Join("/run", "user", strconv.Itoa(d.uid()))
+		d.cfg.LookupEnv = func(key string) (string, bool) {
+			if value, ok := lookup(key); ok || key != "XDG_RUNTIME_DIR" {
+				return value, ok
+			}
+			return runtimeDir, true
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package toolpaths_test
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// newSudoDirs simulates running as root through sudo or doas on behalf of
+// the current user, so the invoker can be looked up without privileges.
+func newSudoDirs(t *testing.T, env map[string]string, fsys toolpaths.FS) (*toolpaths.PlatformDirs, *user.User) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("sudo detection is Unix-only")
+	}
+	current, err := user.Current()
+	require.NoError(t, err)
+	for key, value := range env {
+		if value == "$USER" {
+			env[key] = current.Username
+		}
+	}
+	env["HOME"] = p("/root")
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		SudoAware: true,
+		LookupEnv: mapEnv(env),
+		UID:       func() int { return 0 },
+		FS:        fsys,
+	})
+	require.NoError(t, err)
+	return dirs, current
+}
+
+// tempDirInHome creates a directory below home, which ChownToInvoker
+// requires, and removes it when the test ends.
+func tempDirInHome(t *testing.T, home string) string {
+	t.Helper()
+	dir, err := os.MkdirTemp(home, ".toolpaths-test-")
+	if err != nil {
+		t.Skipf("home directory is not writable: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func TestSudoAware(t *testing.T) {
+	dirs, current := newSudoDirs(t, map[string]string{
+		"SUDO_USER": "$USER",
+		"SUDO_UID":  "4242",
+		"SUDO_GID":  "4343",
+	}, nil)
+
+	inv, ok := dirs.Invoker()
+	require.True(t, ok)
+	assert.Equal(t, toolpaths.Invoker{
+		Via: "sudo", Name: current.Username, UID: 4242, GID: 4343, Home: current.HomeDir,
+	}, inv)
+
+	assert.Equal(t, p(current.HomeDir, ".config", "myapp"), dirs.UserConfigDir())
+	rt, err := dirs.UserRuntimeDir()
+	require.NoError(t, err)
+	assert.Equal(t, p("/run", "user", "4242", "myapp"), rt)
+	assert.Equal(t, p("/etc", "xdg", "myapp"), dirs.SystemConfigDir(), "system directories are unaffected")
+
+	diag := dirs.Diagnose()
+	assert.Contains(t, diag.String(), "Invoker:   "+current.Username+" (uid 4242, via sudo)")
+}
+
+func TestSudoAwareKeepsHomeDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sudo detection is Unix-only")
+	}
+	current, err := user.Current()
+	require.NoError(t, err)
+	home := p(testBase(), "home")
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		SudoAware: true,
+		HomeDir:   home,
+		LookupEnv: mapEnv(map[string]string{"SUDO_USER": current.Username}),
+		UID:       func() int { return 0 },
+	})
+	require.NoError(t, err)
+
+	_, ok := dirs.Invoker()
+	require.True(t, ok)
+	assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir(), "an explicit HomeDir wins over the invoker's")
+}
+
+func TestDoasAware(t *testing.T) {
+	dirs, current := newSudoDirs(t, map[string]string{"DOAS_USER": "$USER"}, nil)
+	inv, ok := dirs.Invoker()
+	require.True(t, ok)
+	assert.Equal(t, "doas", inv.Via)
+	assert.Equal(t, current.Uid, strconv.Itoa(inv.UID))
+	assert.Equal(t, p(current.HomeDir, ".local", "share", "myapp"), dirs.UserDataDir())
+}
+
+func TestSudoAwareNotElevated(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("SUDO_USER", "someone")
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Platform:  toolpaths.PlatformLinux,
+		SudoAware: true,
+		UID:       func() int { return 1000 },
+	})
+	require.NoError(t, err)
+	_, ok := dirs.Invoker()
+	assert.False(t, ok, "SUDO_USER left over in a non-root shell is ignored")
+	assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir())
+	require.NoError(t, dirs.ChownToInvoker(home), "no-op without an invoker")
+}
+
+func TestChownToInvoker(t *testing.T) {
+	dirs, current := newSudoDirs(t, map[string]string{"SUDO_USER": "$USER"}, nil)
+
+	// Giving a directory to its current owner needs no privileges.
+	dir := tempDirInHome(t, current.HomeDir)
+	require.NoError(t, dirs.ChownToInvoker(dir))
+	require.NoError(t, dirs.ChownToInvoker(current.HomeDir), "the home itself is left alone")
+
+	err := dirs.ChownToInvoker(p(dir, "missing"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// Root-owned directories outside the home are not the invoker's.
+	outside := p(t.TempDir(), "myapp")
+	require.NoError(t, os.Mkdir(outside, 0o700))
+	err = dirs.ChownToInvoker(outside)
+	require.ErrorIs(t, err, toolpaths.ErrChownOutsideHome)
+	require.ErrorIs(t, dirs.ChownToInvoker(p(current.HomeDir, "..", "elsewhere")), toolpaths.ErrChownOutsideHome)
+
+	inMemory, _ := newSudoDirs(t, map[string]string{"SUDO_USER": "$USER"}, toolpaths.NewMapFS())
+	require.NoError(t, inMemory.ChownToInvoker(p(current.HomeDir, ".config", "myapp")), "MapFS cannot change owners")
+}
+
+func TestChownToInvokerRefusesSymlinks(t *testing.T) {
+	dirs, current := newSudoDirs(t, map[string]string{"SUDO_USER": "$USER"}, nil)
+
+	// A user who can swap a directory for a symlink to /etc must not get
+	// /etc by running a setup command through sudo.
+	tmp := tempDirInHome(t, current.HomeDir)
+	target := p(t.TempDir(), "etc")
+	require.NoError(t, os.Mkdir(target, 0o755))
+	link := p(tmp, "link")
+	require.NoError(t, os.Symlink(target, link))
+
+	err := dirs.ChownToInvoker(link)
+	require.ErrorIs(t, err, toolpaths.ErrChownSymlink)
+	assert.Contains(t, err.Error(), link)
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package toolpaths_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChownToInvokerOnlyRootOwned(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing owners needs root")
+	}
+	dirs, current := newSudoDirs(t, map[string]string{"SUDO_USER": "$USER", "SUDO_UID": "4242", "SUDO_GID": "4343"}, nil)
+	owner := func(path string) (uint32, uint32) {
+		fi, err := os.Lstat(path)
+		require.NoError(t, err)
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		require.True(t, ok)
+		return st.Uid, st.Gid
+	}
+
+	created := p(tempDirInHome(t, current.HomeDir), "myapp")
+	require.NoError(t, os.Mkdir(created, 0o700))
+	require.NoError(t, dirs.ChownToInvoker(created))
+	uid, gid := owner(created)
+	assert.Equal(t, []uint32{4242, 4343}, []uint32{uid, gid}, "a directory root just created goes to the invoker")
+
+	someones := p(tempDirInHome(t, current.HomeDir), "shared")
+	require.NoError(t, os.Mkdir(someones, 0o755))
+	require.NoError(t, os.Chown(someones, 1234, 1234))
+	require.NoError(t, dirs.ChownToInvoker(someones))
+	uid, _ = owner(someones)
+	assert.Equal(t, uint32(1234), uid, "a directory that already has an owner keeps it")
+}
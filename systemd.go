@@ -0,0 +1,157 @@
+package toolpaths
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SystemdMode selects which directories honor the service directory
+// variables systemd exports for units with ConfigurationDirectory=,
+// StateDirectory=, CacheDirectory=, LogsDirectory=, and RuntimeDirectory=.
+type SystemdMode int
+
+const (
+	// SystemdOff ignores the systemd variables. This is the zero value.
+	SystemdOff SystemdMode = iota
+
+	// SystemdSystem applies the variables to the system directories, for
+	// services run by the system manager.
+	SystemdSystem
+
+	// SystemdUser applies the variables to the user directories, for
+	// --user units.
+	SystemdUser
+)
+
+func (m SystemdMode) String() string {
+	switch m {
+	case SystemdOff:
+		return "off"
+	case SystemdSystem:
+		return "system"
+	case SystemdUser:
+		return "user"
+	default:
+		return "unknown"
+	}
+}
+
+// Systemd service directory variables.
+const (
+	systemdConfigEnv      = "CONFIGURATION_DIRECTORY"
+	systemdStateEnv       = "STATE_DIRECTORY"
+	systemdCacheEnv       = "CACHE_DIRECTORY"
+	systemdLogsEnv        = "LOGS_DIRECTORY"
+	systemdRuntimeEnv     = "RUNTIME_DIRECTORY"
+	systemdCredentialsEnv = "CREDENTIALS_DIRECTORY"
+)
+
+// systemdEnvNames lists every variable systemd may export for a service.
+var systemdEnvNames = []string{
+	systemdConfigEnv,
+	systemdStateEnv,
+	systemdCacheEnv,
+	systemdLogsEnv,
+	systemdRuntimeEnv,
+	systemdCredentialsEnv,
+}
+
+// CredentialsDir returns $CREDENTIALS_DIRECTORY, the directory systemd
+// populates for units with LoadCredential= or SetCredential=. Returns
+// false if the variable is unset. It does not depend on Config.Systemd.
+func (d *PlatformDirs) CredentialsDir() (string, bool) {
+	dir := d.getenv(systemdCredentialsEnv)
+	return dir, dir != ""
+}
+
+// CredentialPath returns the path of the named credential within
+// CredentialsDir. Returns false if no credentials directory is set.
+// Does not check if the credential exists.
+func (d *PlatformDirs) CredentialPath(name string) (string, bool) {
+	dir, ok := d.CredentialsDir()
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(dir, name), true
+}
+
+// ---------------------------------------------------------------------
+// Internal: systemd resolution
+// ---------------------------------------------------------------------
+
+// systemdEnv returns the systemd variable that applies to dt under the
+// configured mode, or "" if none does. systemd has no data directory.
+func (d *PlatformDirs) systemdEnv(dt dirType) string {
+	var user bool
+	switch dt { //nolint:exhaustive // data and managed dirs have no systemd variable
+	case userConfig, userState, userCache, userLog, userRuntime:
+		user = true
+	case systemConfig, systemState, systemCache, systemLog, systemRuntime:
+		user = false
+	default:
+		return ""
+	}
+	if (user && d.cfg.Systemd != SystemdUser) || (!user && d.cfg.Systemd != SystemdSystem) {
+		return ""
+	}
+
+	switch dt { //nolint:exhaustive // filtered above
+	case userConfig, systemConfig:
+		return systemdConfigEnv
+	case userState, systemState:
+		return systemdStateEnv
+	case userCache, systemCache:
+		return systemdCacheEnv
+	case userLog, systemLog:
+		return systemdLogsEnv
+	default:
+		return systemdRuntimeEnv
+	}
+}
+
+// systemdDirs returns the directories systemd set for dt, in order. The
+// variables are colon-separated lists of absolute paths that already name
+// the service's directories, so the app path is not appended. Returns nil
+// if the mode does not cover dt, the variable is unset, or an EnvOverrides
+// variable or portable mode takes precedence.
+func (d *PlatformDirs) systemdDirs(dt dirType) []string {
+	name := d.systemdEnv(dt)
+	if name == "" || d.fromEnvOverride(dt) != "" || d.portableDir(dt) != "" {
+		return nil
+	}
+	var dirs []string
+	for _, dir := range strings.Split(d.getenv(name), ":") {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	return dirs
+}
+
+// overrideDir returns the EnvOverrides value for dt, or else the portable
+// directory, or else the first systemd directory, or "" if none applies.
+func (d *PlatformDirs) overrideDir(dt dirType) string {
+	if dir := d.fromEnvOverride(dt); dir != "" {
+		return dir
+	}
+	if dir := d.portableDir(dt); dir != "" {
+		return dir
+	}
+	if dirs := d.systemdDirs(dt); len(dirs) > 0 {
+		return dirs[0]
+	}
+	return ""
+}
+
+// withSystemdDirs returns the systemd directories for dt followed by dirs,
+// dropping empty entries and duplicates.
+func (d *PlatformDirs) withSystemdDirs(dt dirType, dirs ...string) []string {
+	var result []string
+	for _, dir := range append(d.systemdDirs(dt), dirs...) {
+		if dir != "" && !slices.Contains(result, dir) {
+			result = append(result, dir)
+		}
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+package toolpaths_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// setSystemdEnv points the systemd service directory variables at
+// directories under home, with two entries for config and state.
+func setSystemdEnv(t *testing.T, home string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("systemd variables use ':' separators, which clash with Windows drive letters")
+	}
+	t.Setenv("CONFIGURATION_DIRECTORY", p(home, "svc", "config-a")+":"+p(home, "svc", "config-b"))
+	t.Setenv("STATE_DIRECTORY", p(home, "svc", "state-a")+":relative:"+p(home, "svc", "state-b"))
+	t.Setenv("CACHE_DIRECTORY", p(home, "svc", "cache"))
+	t.Setenv("LOGS_DIRECTORY", p(home, "svc", "logs"))
+	t.Setenv("RUNTIME_DIRECTORY", p(home, "svc", "run"))
+}
+
+func TestSystemdSystemMode(t *testing.T) {
+	home := setTestHomeXDG(t)
+	setSystemdEnv(t, home)
+	t.Setenv("XDG_CONFIG_DIRS", p(home, "xdg"))
+	t.Setenv("XDG_RUNTIME_DIR", p(home, "xdg-run"))
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformLinux,
+		Systemd:  toolpaths.SystemdSystem,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, "svc", "config-a"), dirs.SystemConfigDir())
+	assert.Equal(t, []string{
+		p(home, "svc", "config-a"),
+		p(home, "svc", "config-b"),
+		p(home, "xdg", "myapp"),
+	}, dirs.SystemConfigDirs())
+	assert.Equal(t, p(home, "svc", "state-a"), dirs.SystemStateDir())
+	assert.Equal(t, p(home, "svc", "cache"), dirs.SystemCacheDir())
+	assert.Equal(t, p(home, "svc", "logs"), dirs.SystemLogDir())
+	assert.Equal(t, p(home, "svc", "run"), dirs.SystemRuntimeDir())
+
+	t.Run("user directories are unaffected", func(t *testing.T) {
+		assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir())
+		assert.Equal(t, []string{p(home, ".local", "state", "myapp")}, dirs.UserStateDirs())
+	})
+
+	t.Run("every entry is searched", func(t *testing.T) {
+		assert.Equal(t, []string{
+			p(home, ".local", "state", "myapp", "db"),
+			p(home, "svc", "state-a", "db"),
+			p(home, "svc", "state-b", "db"),
+		}, dirs.AllStatePaths("db"))
+		assert.Contains(t, dirs.AllConfigPaths("config.toml"), p(home, "svc", "config-b", "config.toml"))
+	})
+
+	t.Run("explain", func(t *testing.T) {
+		x, err := dirs.Explain("system-state")
+		require.NoError(t, err)
+		step, ok := x.Chosen()
+		require.True(t, ok)
+		assert.Equal(t, toolpaths.StrategySystemd, step.Strategy)
+		assert.Equal(t, "STATE_DIRECTORY", step.EnvVar)
+		assert.Equal(t, x.Path, step.Path)
+	})
+
+	t.Run("diagnose", func(t *testing.T) {
+		diag := dirs.Diagnose()
+		assert.Len(t, findDirInfo(t, diag, "system-state"), 2)
+		var names []string
+		for _, v := range diag.Env {
+			names = append(names, v.Name)
+		}
+		assert.Contains(t, names, "STATE_DIRECTORY")
+		assert.Contains(t, names, "CREDENTIALS_DIRECTORY")
+	})
+}
+
+func TestSystemdUserMode(t *testing.T) {
+	home := setTestHomeXDG(t)
+	setSystemdEnv(t, home)
+	t.Setenv("XDG_CONFIG_DIRS", p(home, "xdg"))
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "myapp",
+		Platform: toolpaths.PlatformLinux,
+		Systemd:  toolpaths.SystemdUser,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, "svc", "config-a"), dirs.UserConfigDir())
+	assert.Equal(t, []string{p(home, "svc", "config-a"), p(home, "svc", "config-b")}, dirs.UserConfigDirs())
+	assert.Equal(t, p(home, "svc", "logs"), dirs.UserLogDir())
+	rt, err := dirs.UserRuntimeDir()
+	require.NoError(t, err)
+	assert.Equal(t, p(home, "svc", "run"), rt)
+	assert.Equal(t, p(home, ".local", "share", "myapp"), dirs.UserDataDir(), "no systemd data dir")
+
+	assert.Equal(t, p(home, "xdg", "myapp"), dirs.SystemConfigDir())
+	assert.Equal(t, "/var/lib/myapp", dirs.SystemStateDir())
+}
+
+func TestSystemdOffAndOverrides(t *testing.T) {
+	home := setTestHomeXDG(t)
+	setSystemdEnv(t, home)
+
+	off, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformLinux})
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/myapp", off.SystemStateDir())
+	assert.Equal(t, p(home, ".config", "myapp"), off.UserConfigDir())
+
+	t.Setenv("MYAPP_STATE", p(home, "override"))
+	overridden, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:      "myapp",
+		Platform:     toolpaths.PlatformLinux,
+		Systemd:      toolpaths.SystemdSystem,
+		EnvOverrides: &toolpaths.EnvOverrides{SystemState: "MYAPP_STATE"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, p(home, "override"), overridden.SystemStateDir())
+	assert.NotContains(t, overridden.AllStatePaths("db"), p(home, "svc", "state-b", "db"))
+}
+
+func TestCredentialsDir(t *testing.T) {
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Platform: toolpaths.PlatformLinux})
+	require.NoError(t, err)
+
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	_, ok := dirs.CredentialsDir()
+	assert.False(t, ok)
+	_, ok = dirs.CredentialPath("token")
+	assert.False(t, ok)
+
+	creds := p(testBase(), "run", "credentials", "myapp.service")
+	t.Setenv("CREDENTIALS_DIRECTORY", creds)
+	dir, ok := dirs.CredentialsDir()
+	require.True(t, ok)
+	assert.Equal(t, creds, dir)
+	token, ok := dirs.CredentialPath("token")
+	require.True(t, ok)
+	assert.Equal(t, p(creds, "token"), token)
+}
+
+func TestSystemdModeString(t *testing.T) {
+	assert.Equal(t, "off", toolpaths.SystemdOff.String())
+	assert.Equal(t, "system", toolpaths.SystemdSystem.String())
+	assert.Equal(t, "user", toolpaths.SystemdUser.String())
+	assert.Equal(t, "unknown", toolpaths.SystemdMode(99).String())
+}
@@ -0,0 +1,235 @@
+package toolpaths
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+)
+
+// ---------------------------------------------------------------------
+// User media directories
+// ---------------------------------------------------------------------
+
+// UserDesktopDir returns the user's desktop directory.
+func (d *PlatformDirs) UserDesktopDir() string {
+	return d.resolveMediaDir(mediaDesktop)
+}
+
+// UserDocumentsDir returns the user's documents directory.
+func (d *PlatformDirs) UserDocumentsDir() string {
+	return d.resolveMediaDir(mediaDocuments)
+}
+
+// UserDownloadDir returns the user's downloads directory.
+func (d *PlatformDirs) UserDownloadDir() string {
+	return d.resolveMediaDir(mediaDownload)
+}
+
+// UserMusicDir returns the user's music directory.
+func (d *PlatformDirs) UserMusicDir() string {
+	return d.resolveMediaDir(mediaMusic)
+}
+
+// UserPicturesDir returns the user's pictures directory.
+func (d *PlatformDirs) UserPicturesDir() string {
+	return d.resolveMediaDir(mediaPictures)
+}
+
+// UserVideosDir returns the user's videos directory (~/Movies on macOS).
+func (d *PlatformDirs) UserVideosDir() string {
+	return d.resolveMediaDir(mediaVideos)
+}
+
+// UserTemplatesDir returns the user's document templates directory.
+func (d *PlatformDirs) UserTemplatesDir() string {
+	return d.resolveMediaDir(mediaTemplates)
+}
+
+// UserPublicDir returns the user's public share directory. On Windows this
+// is the shared Public folder rather than a per-user one.
+func (d *PlatformDirs) UserPublicDir() string {
+	return d.resolveMediaDir(mediaPublic)
+}
+
+// ---------------------------------------------------------------------
+// Internal: media directory resolution
+// ---------------------------------------------------------------------
+
+// mediaDir identifies a well-known user directory. Unlike dirType, these
+// are shared by all applications, so the app path is never appended.
+type mediaDir int
+
+const (
+	mediaDesktop mediaDir = iota
+	mediaDocuments
+	mediaDownload
+	mediaMusic
+	mediaPictures
+	mediaVideos
+	mediaTemplates
+	mediaPublic
+)
+
+// xdgName returns the XDG_{NAME}_DIR key for md.
+func (md mediaDir) xdgName() string {
+	switch md {
+	case mediaDesktop:
+		return "XDG_DESKTOP_DIR"
+	case mediaDocuments:
+		return "XDG_DOCUMENTS_DIR"
+	case mediaDownload:
+		return "XDG_DOWNLOAD_DIR"
+	case mediaMusic:
+		return "XDG_MUSIC_DIR"
+	case mediaPictures:
+		return "XDG_PICTURES_DIR"
+	case mediaVideos:
+		return "XDG_VIDEOS_DIR"
+	case mediaTemplates:
+		return "XDG_TEMPLATES_DIR"
+	case mediaPublic:
+		return "XDG_PUBLICSHARE_DIR"
+	default:
+		return ""
+	}
+}
+
+// defaultName returns the home subdirectory xdg-user-dirs creates for md.
+func (md mediaDir) defaultName() string {
+	switch md {
+	case mediaDesktop:
+		return "Desktop"
+	case mediaDocuments:
+		return "Documents"
+	case mediaDownload:
+		return "Downloads"
+	case mediaMusic:
+		return "Music"
+	case mediaPictures:
+		return "Pictures"
+	case mediaVideos:
+		return "Videos"
+	case mediaTemplates:
+		return "Templates"
+	case mediaPublic:
+		return "Public"
+	default:
+		return ""
+	}
+}
+
+// resolveMediaDir follows the same precedence as the base directories:
+// an XDG_*_DIR variable is respected everywhere, user-dirs.dirs applies on
+// XDG platforms (and with XDGOnAllPlatforms), otherwise the platform
+// convention is used.
+func (d *PlatformDirs) resolveMediaDir(md mediaDir) string {
+	if dir := d.getenv(md.xdgName()); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	if d.xdgPrimary() {
+		if dir, ok := d.readUserDirs()[md.xdgName()]; ok {
+			return dir
+		}
+		return filepath.Join(d.homeDir(), md.defaultName())
+	}
+
+	switch d.platform { //nolint:exhaustive // XDG platforms handled above
+	case PlatformMacOS:
+		if md == mediaVideos {
+			return filepath.Join(d.homeDir(), "Movies")
+		}
+		return filepath.Join(d.homeDir(), md.defaultName())
+	case PlatformWindows:
+		return d.windowsMediaDir(md)
+	default:
+		return filepath.Join(d.homeDir(), md.defaultName())
+	}
+}
+
+// userDirsFile returns the path of user-dirs.dirs.
+func (d *PlatformDirs) userDirsFile() string {
+	configHome := d.xdgEnv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(d.homeDir(), ".config")
+	}
+	return filepath.Join(configHome, "user-dirs.dirs")
+}
+
+// readUserDirs parses user-dirs.dirs from the configured FS. A missing or
+// unreadable file yields an empty map.
+func (d *PlatformDirs) readUserDirs() map[string]string {
+	dirs := map[string]string{}
+	f, err := d.fsys.Open(d.userDirsFile())
+	if err != nil {
+		return dirs
+	}
+	defer f.Close()
+
+	home := d.homeDir()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key, dir, ok := parseUserDirsLine(scanner.Text(), home); ok {
+			dirs[key] = dir
+		}
+	}
+	return dirs
+}
+
+// parseUserDirsLine parses one line of user-dirs.dirs. xdg-user-dirs only
+// supports XDG_xxx_DIR="$HOME/yyy", where yyy is shell-escaped and
+// relative to the home directory, and XDG_xxx_DIR="/yyy" for absolute
+// paths. Anything else, including comments, is skipped.
+func parseUserDirsLine(line, home string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(line, "=")
+	key = strings.TrimSpace(key)
+	if !ok || !strings.HasPrefix(key, "XDG_") || !strings.HasSuffix(key, "_DIR") {
+		return "", "", false
+	}
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", "", false
+	}
+	value = shellUnescape(value[1 : len(value)-1])
+
+	switch {
+	case value == "$HOME" || value == "$HOME/":
+		// Setting a directory to $HOME disables it; report the home directory.
+		return key, home, true
+	case strings.HasPrefix(value, "$HOME/"):
+		return key, filepath.Join(home, filepath.FromSlash(strings.TrimPrefix(value, "$HOME/"))), true
+	case strings.HasPrefix(value, "/"):
+		return key, filepath.FromSlash(value), true
+	default:
+		return "", "", false
+	}
+}
+
+// shellUnescape removes the backslash escapes used inside double quotes.
+func shellUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// windowsMediaDirDefault returns the default Known Folder location for md
+// relative to the home directory, used off Windows and in tests.
+func (d *PlatformDirs) windowsMediaDirDefault(md mediaDir) string {
+	switch md { //nolint:exhaustive // the rest are directly under the profile
+	case mediaTemplates:
+		return filepath.Join(d.windowsRoamingAppData(), "Microsoft", "Windows", "Templates")
+	case mediaPublic:
+		return filepath.Join(filepath.Dir(d.homeDir()), "Public")
+	default:
+		return filepath.Join(d.homeDir(), md.defaultName())
+	}
+}
@@ -0,0 +1,125 @@
+package toolpaths_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+// setTestMediaEnv isolates HOME and clears the XDG media directory
+// variables.
+func setTestMediaEnv(t *testing.T) string {
+	t.Helper()
+	home := setTestHomeXDG(t)
+	for _, name := range []string{
+		"XDG_DESKTOP_DIR", "XDG_DOCUMENTS_DIR", "XDG_DOWNLOAD_DIR", "XDG_MUSIC_DIR",
+		"XDG_PICTURES_DIR", "XDG_VIDEOS_DIR", "XDG_TEMPLATES_DIR", "XDG_PUBLICSHARE_DIR",
+	} {
+		t.Setenv(name, "")
+	}
+	return home
+}
+
+func TestUserMediaDirsDefaults(t *testing.T) {
+	home := setTestMediaEnv(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:       "myapp",
+		Platform:      toolpaths.PlatformLinux,
+		FS:            toolpaths.NewMapFS(),
+		IgnoreSandbox: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, "Desktop"), dirs.UserDesktopDir())
+	assert.Equal(t, p(home, "Documents"), dirs.UserDocumentsDir())
+	assert.Equal(t, p(home, "Downloads"), dirs.UserDownloadDir())
+	assert.Equal(t, p(home, "Music"), dirs.UserMusicDir())
+	assert.Equal(t, p(home, "Pictures"), dirs.UserPicturesDir())
+	assert.Equal(t, p(home, "Videos"), dirs.UserVideosDir())
+	assert.Equal(t, p(home, "Templates"), dirs.UserTemplatesDir())
+	assert.Equal(t, p(home, "Public"), dirs.UserPublicDir())
+}
+
+func TestUserMediaDirsFromUserDirsFile(t *testing.T) {
+	home := setTestMediaEnv(t)
+	mem := toolpaths.NewMapFS()
+	require.NoError(t, mem.WriteFile(p(home, ".config", "user-dirs.dirs"), []byte(`# generated by xdg-user-dirs-update
+XDG_DESKTOP_DIR="$HOME/Schreibtisch"
+XDG_DOCUMENTS_DIR="$HOME/My \"Docs\""
+XDG_DOWNLOAD_DIR="/srv/downloads"
+XDG_MUSIC_DIR="$HOME"
+XDG_PICTURES_DIR=relative/ignored
+XDG_VIDEOS_DIR="~/ignored"
+`), 0o644))
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:       "myapp",
+		Platform:      toolpaths.PlatformLinux,
+		FS:            mem,
+		IgnoreSandbox: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, "Schreibtisch"), dirs.UserDesktopDir())
+	assert.Equal(t, p(home, `My "Docs"`), dirs.UserDocumentsDir())
+	assert.Equal(t, p("/srv", "downloads"), dirs.UserDownloadDir())
+	assert.Equal(t, home, dirs.UserMusicDir(), "$HOME disables the directory")
+	assert.Equal(t, p(home, "Pictures"), dirs.UserPicturesDir(), "unquoted values are ignored")
+	assert.Equal(t, p(home, "Videos"), dirs.UserVideosDir(), "only $HOME is expanded")
+
+	t.Run("XDG_CONFIG_HOME", func(t *testing.T) {
+		configHome := p(home, "elsewhere")
+		t.Setenv("XDG_CONFIG_HOME", configHome)
+		require.NoError(t, mem.WriteFile(p(configHome, "user-dirs.dirs"),
+			[]byte(`XDG_DESKTOP_DIR="$HOME/Bureau"`+"\n"), 0o644))
+		assert.Equal(t, p(home, "Bureau"), dirs.UserDesktopDir())
+	})
+
+	t.Run("environment wins", func(t *testing.T) {
+		t.Setenv("XDG_DESKTOP_DIR", p(home, "from-env"))
+		assert.Equal(t, p(home, "from-env"), dirs.UserDesktopDir())
+	})
+}
+
+func TestUserMediaDirsMacOS(t *testing.T) {
+	home := setTestMediaEnv(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:       "myapp",
+		Platform:      toolpaths.PlatformMacOS,
+		FS:            toolpaths.NewMapFS(),
+		IgnoreSandbox: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, "Documents"), dirs.UserDocumentsDir())
+	assert.Equal(t, p(home, "Movies"), dirs.UserVideosDir())
+	assert.Equal(t, p(home, "Public"), dirs.UserPublicDir())
+}
+
+func TestUserMediaDirsWindows(t *testing.T) {
+	home := setTestMediaEnv(t)
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:       "myapp",
+		Platform:      toolpaths.PlatformWindows,
+		FS:            toolpaths.NewMapFS(),
+		IgnoreSandbox: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, p(home, "Downloads"), dirs.UserDownloadDir())
+	assert.Equal(t, p(home, "AppData", "Roaming", "Microsoft", "Windows", "Templates"), dirs.UserTemplatesDir())
+	assert.Equal(t, p(home, "..", "Public"), dirs.UserPublicDir())
+}
+
+func TestFakeDirsUserMediaDirs(t *testing.T) {
+	base := testBase()
+	fake := toolpaths.NewFakeDirs(base)
+
+	assert.Equal(t, p(base, "home", "Desktop"), fake.UserDesktopDir())
+	assert.Equal(t, p(base, "home", "Videos"), fake.UserVideosDir())
+
+	fake.UserDownloadDirVal = p(base, "dl")
+	assert.Equal(t, p(base, "dl"), fake.UserDownloadDir())
+}
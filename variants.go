@@ -0,0 +1,266 @@
+package toolpaths
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileResolver generates the filenames to check for a basename.
+type FileResolver interface {
+	// Candidates returns all filenames to check for a given basename,
+	// in priority order.
+	Candidates(basename string) []string
+}
+
+// FileResolverFunc adapts a function to the FileResolver interface.
+type FileResolverFunc func(basename string) []string
+
+// Candidates calls f(basename).
+func (f FileResolverFunc) Candidates(basename string) []string {
+	return f(basename)
+}
+
+// VariantStyle selects the naming patterns checked for a basename.
+type VariantStyle int
+
+const (
+	// VariantBoth checks "name.ext" and ".name.ext".
+	VariantBoth VariantStyle = iota
+	// VariantDotted checks only ".name.ext".
+	VariantDotted
+	// VariantPlain checks only "name.ext".
+	VariantPlain
+	// VariantCustom uses a provided FileResolver.
+	VariantCustom
+)
+
+func (s VariantStyle) String() string {
+	switch s {
+	case VariantBoth:
+		return "both"
+	case VariantDotted:
+		return "dotted"
+	case VariantPlain:
+		return "plain"
+	case VariantCustom:
+		return "custom"
+	default:
+		return "unknown"
+	}
+}
+
+// Candidates returns the filenames to check for basename under this style.
+// A basename that already starts with a dot is returned as-is.
+// VariantCustom has no rules of its own and returns basename unchanged.
+func (s VariantStyle) Candidates(basename string) []string {
+	if strings.HasPrefix(basename, ".") {
+		return []string{basename}
+	}
+	switch s {
+	case VariantBoth:
+		return []string{basename, "." + basename}
+	case VariantDotted:
+		return []string{"." + basename}
+	case VariantPlain, VariantCustom:
+		return []string{basename}
+	default:
+		return []string{basename}
+	}
+}
+
+// ExtensionResolver checks a basename with each of a list of extensions.
+// If the basename already ends in one of the extensions, that extension is
+// replaced. Each name is expanded according to Style, so the zero value
+// checks both plain and dotted names.
+type ExtensionResolver struct {
+	// Extensions to try, in order, including the leading dot (e.g., ".yaml").
+	Extensions []string
+
+	// Style controls plain vs dotted naming for each extension.
+	Style VariantStyle
+}
+
+// Candidates returns basename with each extension applied.
+func (r ExtensionResolver) Candidates(basename string) []string {
+	stem := basename
+	if ext := filepath.Ext(basename); slices.Contains(r.Extensions, ext) {
+		stem = strings.TrimSuffix(basename, ext)
+	}
+	var candidates []string
+	for _, ext := range r.Extensions {
+		candidates = append(candidates, r.Style.Candidates(stem+ext)...)
+	}
+	return candidates
+}
+
+// Built-in resolvers.
+var (
+	// YAMLResolver checks .yaml and .yml extensions.
+	YAMLResolver FileResolver = ExtensionResolver{
+		Extensions: []string{".yaml", ".yml"},
+		Style:      VariantPlain,
+	}
+
+	// JSONResolver checks .json extension.
+	JSONResolver FileResolver = ExtensionResolver{
+		Extensions: []string{".json"},
+		Style:      VariantPlain,
+	}
+
+	// MultiFormatResolver checks .yaml, .yml, .json, .toml.
+	MultiFormatResolver FileResolver = ExtensionResolver{
+		Extensions: []string{".yaml", ".yml", ".json", ".toml"},
+		Style:      VariantPlain,
+	}
+)
+
+// Variants returns a FileResolver that checks a fixed list of filenames,
+// ignoring the basename it is given. Useful for tools that accept several
+// unrelated names, e.g. Variants("config.yaml", "config.toml", ".myapprc").
+func Variants(names ...string) FileResolver {
+	return FileResolverFunc(func(string) []string {
+		return slices.Clone(names)
+	})
+}
+
+// VariantMatch describes an existing file found through a FileResolver.
+type VariantMatch struct {
+	Path    string // Full path to the matched file
+	Dir     string // Directory containing the file
+	Variant string // The candidate filename that matched
+
+	// Conflicts lists other variants that also exist in Dir, in resolver
+	// order. Non-empty means the match is ambiguous.
+	Conflicts []string
+}
+
+// Ambiguous reports whether more than one variant exists in the same directory.
+func (m VariantMatch) Ambiguous() bool {
+	return len(m.Conflicts) > 0
+}
+
+// resolverOrDefault returns resolver, or VariantBoth if resolver is nil.
+func resolverOrDefault(resolver FileResolver) FileResolver {
+	if resolver == nil {
+		return VariantBoth
+	}
+	return resolver
+}
+
+// existingVariants returns one VariantMatch per directory containing at
+// least one candidate, in directory priority order. allPaths is an All*Paths
+// method; calling it with an empty name yields the directories themselves.
+func existingVariants(
+	basename string,
+	resolver FileResolver,
+	allPaths func(string) []string,
+	exists func(string) bool,
+) []VariantMatch {
+	candidates := resolverOrDefault(resolver).Candidates(basename)
+	var matches []VariantMatch
+	for _, dir := range allPaths("") {
+		if dir == "" {
+			continue
+		}
+		var m VariantMatch
+		for _, name := range candidates {
+			p := filepath.Join(dir, name)
+			if !exists(p) {
+				continue
+			}
+			if m.Path == "" {
+				m = VariantMatch{Path: p, Dir: dir, Variant: name}
+			} else {
+				m.Conflicts = append(m.Conflicts, name)
+			}
+		}
+		if m.Path != "" {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// findVariant returns the first entry of existingVariants.
+func findVariant(
+	basename string,
+	resolver FileResolver,
+	allPaths func(string) []string,
+	exists func(string) bool,
+) (VariantMatch, bool) {
+	matches := existingVariants(basename, resolver, allPaths, exists)
+	if len(matches) == 0 {
+		return VariantMatch{}, false
+	}
+	return matches[0], true
+}
+
+// ---------------------------------------------------------------------
+// PlatformDirs variant lookups
+// ---------------------------------------------------------------------
+
+// FindConfigFileVariant finds the first config file matching any variant of
+// basename produced by resolver (VariantBoth if nil), searching directories
+// in the same order as FindConfigFile. Within a directory, the resolver's
+// order decides which variant wins; other existing variants are reported
+// as conflicts.
+func (d *PlatformDirs) FindConfigFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, d.AllConfigPaths, d.fileExists)
+}
+
+// ExistingConfigFileVariants returns the matching variant in each config
+// directory that contains one, in priority order.
+func (d *PlatformDirs) ExistingConfigFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, d.AllConfigPaths, d.fileExists)
+}
+
+// FindDataFileVariant is FindConfigFileVariant for data directories.
+func (d *PlatformDirs) FindDataFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, d.AllDataPaths, d.fileExists)
+}
+
+// ExistingDataFileVariants is ExistingConfigFileVariants for data directories.
+func (d *PlatformDirs) ExistingDataFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, d.AllDataPaths, d.fileExists)
+}
+
+// FindStateFileVariant is FindConfigFileVariant for state directories.
+func (d *PlatformDirs) FindStateFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, d.AllStatePaths, d.fileExists)
+}
+
+// ExistingStateFileVariants is ExistingConfigFileVariants for state directories.
+func (d *PlatformDirs) ExistingStateFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, d.AllStatePaths, d.fileExists)
+}
+
+// FindCacheFileVariant is FindConfigFileVariant for cache directories.
+func (d *PlatformDirs) FindCacheFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, d.AllCachePaths, d.fileExists)
+}
+
+// ExistingCacheFileVariants is ExistingConfigFileVariants for cache directories.
+func (d *PlatformDirs) ExistingCacheFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, d.AllCachePaths, d.fileExists)
+}
+
+// FindLogFileVariant is FindConfigFileVariant for log directories.
+func (d *PlatformDirs) FindLogFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, d.AllLogPaths, d.fileExists)
+}
+
+// ExistingLogFileVariants is ExistingConfigFileVariants for log directories.
+func (d *PlatformDirs) ExistingLogFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, d.AllLogPaths, d.fileExists)
+}
+
+// FindRuntimeFileVariant is FindConfigFileVariant for runtime directories.
+func (d *PlatformDirs) FindRuntimeFileVariant(basename string, resolver FileResolver) (VariantMatch, bool) {
+	return findVariant(basename, resolver, d.AllRuntimePaths, d.fileExists)
+}
+
+// ExistingRuntimeFileVariants is ExistingConfigFileVariants for runtime directories.
+func (d *PlatformDirs) ExistingRuntimeFileVariants(basename string, resolver FileResolver) []VariantMatch {
+	return existingVariants(basename, resolver, d.AllRuntimePaths, d.fileExists)
+}
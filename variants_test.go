@@ -0,0 +1,201 @@
+package toolpaths_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestVariantStyleCandidates(t *testing.T) {
+	tests := []struct {
+		style    toolpaths.VariantStyle
+		basename string
+		want     []string
+	}{
+		{toolpaths.VariantBoth, "config.yaml", []string{"config.yaml", ".config.yaml"}},
+		{toolpaths.VariantDotted, "config.yaml", []string{".config.yaml"}},
+		{toolpaths.VariantPlain, "config.yaml", []string{"config.yaml"}},
+		{toolpaths.VariantBoth, ".myapprc", []string{".myapprc"}},
+		{toolpaths.VariantDotted, ".myapprc", []string{".myapprc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style.String()+"/"+tt.basename, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.style.Candidates(tt.basename))
+		})
+	}
+}
+
+func TestExtensionResolver(t *testing.T) {
+	assert.Equal(t, []string{"config.yaml", "config.yml"}, toolpaths.YAMLResolver.Candidates("config"))
+	assert.Equal(t, []string{"config.yaml", "config.yml"}, toolpaths.YAMLResolver.Candidates("config.yml"))
+	assert.Equal(t, []string{"config.json"}, toolpaths.JSONResolver.Candidates("config"))
+	assert.Equal(t,
+		[]string{"config.yaml", "config.yml", "config.json", "config.toml"},
+		toolpaths.MultiFormatResolver.Candidates("config"))
+
+	both := toolpaths.ExtensionResolver{Extensions: []string{".yaml", ".toml"}}
+	assert.Equal(t,
+		[]string{"config.yaml", ".config.yaml", "config.toml", ".config.toml"},
+		both.Candidates("config"))
+}
+
+func TestVariantsResolver(t *testing.T) {
+	r := toolpaths.Variants("config.yaml", ".myapprc")
+	assert.Equal(t, []string{"config.yaml", ".myapprc"}, r.Candidates("ignored"))
+}
+
+func TestFindConfigFileVariant(t *testing.T) {
+	home := setTestHomeXDG(t)
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(home, "etc"))
+
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:  "testapp",
+		Platform: toolpaths.PlatformLinux,
+	})
+	require.NoError(t, err)
+
+	userDir := filepath.Join(home, ".config", "testapp")
+	sysDir := filepath.Join(home, "etc", "testapp")
+	require.NoError(t, os.MkdirAll(userDir, 0o755))
+	require.NoError(t, os.MkdirAll(sysDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "config.yml"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(sysDir, "config.yaml"), nil, 0o644))
+
+	resolver := toolpaths.Variants("config.yaml", "config.yml", "config.toml", ".testapprc", ".config.yaml")
+
+	m, found := dirs.FindConfigFileVariant("config", resolver)
+	require.True(t, found)
+	assert.Equal(t, filepath.Join(userDir, "config.yml"), m.Path)
+	assert.Equal(t, userDir, m.Dir)
+	assert.Equal(t, "config.yml", m.Variant)
+	assert.False(t, m.Ambiguous())
+
+	matches := dirs.ExistingConfigFileVariants("config", resolver)
+	require.Len(t, matches, 2)
+	assert.Equal(t, filepath.Join(sysDir, "config.yaml"), matches[1].Path)
+
+	_, found = dirs.FindDataFileVariant("config", resolver)
+	assert.False(t, found)
+}
+
+func TestFindConfigFileVariantAmbiguous(t *testing.T) {
+	base := testBase()
+	fake := toolpaths.NewFakeDirs(base)
+	fake.SetExisting(p(base, "config", "config.yaml"))
+	fake.SetExisting(p(base, "config", ".config.yaml"))
+
+	m, found := fake.FindConfigFileVariant("config.yaml", nil)
+	require.True(t, found)
+	assert.Equal(t, p(base, "config", "config.yaml"), m.Path)
+	assert.Equal(t, "config.yaml", m.Variant)
+	assert.True(t, m.Ambiguous())
+	assert.Equal(t, []string{".config.yaml"}, m.Conflicts)
+}
+
+func TestFakeDirsFileVariantsAllTypes(t *testing.T) {
+	base := testBase()
+	fake := toolpaths.NewFakeDirs(base)
+	fake.SetExisting(p(base, "data", "db.json"))
+	fake.SetExisting(p(base, "system", "state", "state.yml"))
+	fake.SetExisting(p(base, "cache", ".index.json"))
+	fake.SetExisting(p(base, "log", "app.log"))
+	fake.SetExisting(p(base, "runtime", "sock"))
+
+	m, found := fake.FindDataFileVariant("db", toolpaths.MultiFormatResolver)
+	require.True(t, found)
+	assert.Equal(t, "db.json", m.Variant)
+
+	states := fake.ExistingStateFileVariants("state", toolpaths.YAMLResolver)
+	require.Len(t, states, 1)
+	assert.Equal(t, p(base, "system", "state", "state.yml"), states[0].Path)
+
+	m, found = fake.FindCacheFileVariant("index.json", toolpaths.VariantDotted)
+	require.True(t, found)
+	assert.Equal(t, ".index.json", m.Variant)
+
+	assert.Len(t, fake.ExistingLogFileVariants("app.log", nil), 1)
+
+	_, found = fake.FindRuntimeFileVariant("sock", toolpaths.VariantPlain)
+	assert.True(t, found)
+}
+
+func TestCascadeFileVariants(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+
+	t.Run("default checks plain and dotted", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project)
+		fake.SetExisting(p(base, "config", ".config.yaml"))
+
+		primary, _, err := c.ResolveFile("config.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "config", ".config.yaml"), primary)
+	})
+
+	t.Run("custom resolver", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, toolpaths.WithFileResolver(toolpaths.MultiFormatResolver))
+		fake.SetExisting(p(base, "config", "config.toml"))
+
+		primary, _, err := c.ResolveFile("config")
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "config", "config.toml"), primary)
+
+		def, err := c.DefaultPath("config", "user")
+		require.NoError(t, err)
+		assert.Equal(t, p(base, "config", "config.yaml"), def)
+	})
+
+	t.Run("dotted only", func(t *testing.T) {
+		c, fake := newTestCascade(t, base, project, toolpaths.WithFileVariants(toolpaths.VariantDotted))
+		fake.SetExisting(p(base, "config", "config.yaml"))
+
+		primary, _, err := c.ResolveFile("config.yaml")
+		require.NoError(t, err)
+		assert.Empty(t, primary)
+	})
+}
+
+func TestCascadeKnownFiles(t *testing.T) {
+	base := testBase()
+	project := p(base, "proj")
+	opts := []toolpaths.CascadeOption{
+		toolpaths.WithProjectDirs(map[string]toolpaths.ProjectDirConfig{
+			"config": {Patterns: []string{".config/{app}", ".{app}"}},
+		}),
+		toolpaths.WithProjectFiles(map[string]toolpaths.ProjectFileConfig{
+			"config": {
+				Variants: []string{"{app}.yaml", ".{app}.yaml"},
+				Dir:      "config",
+				Subdirs:  []string{"conf.d"},
+			},
+			"root": {Variants: []string{"{app}.toml"}},
+		}),
+	}
+
+	c, fake := newTestCascade(t, base, project, opts...)
+	fake.SetExisting(p(project, ".git"))
+	fake.SetExisting(p(project, ".myapp", "conf.d", ".myapp.yaml"))
+	fake.SetExisting(p(project, ".myapp", "myapp.yaml"))
+	fake.SetExisting(p(project, "myapp.toml"))
+
+	primary, alternates, err := c.ResolveKnownFile("config")
+	require.NoError(t, err)
+	assert.Equal(t, p(project, ".myapp", "myapp.yaml"), primary)
+	assert.Equal(t, []string{p(project, ".myapp", "conf.d", ".myapp.yaml")}, alternates)
+
+	def, err := c.DefaultKnownFile("config")
+	require.NoError(t, err)
+	assert.Equal(t, p(project, ".config", "myapp", "myapp.yaml"), def)
+
+	primary, _, err = c.ResolveKnownFile("root")
+	require.NoError(t, err)
+	assert.Equal(t, p(project, "myapp.toml"), primary)
+
+	_, _, err = c.ResolveKnownFile("nope")
+	require.ErrorIs(t, err, toolpaths.ErrProjectFileNotFound)
+}
@@ -0,0 +1,276 @@
+package toolpaths
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Versions returns the version directories that exist beside the current
+// one for a user directory kind ("user-config", "user-data", "user-cache",
+// "user-state", or "user-log"), newest first. Only names that parse as
+// semantic versions ("3", "v2", "2.1.0", "1.0.0-beta") are listed, and the
+// current Version is included if its directory exists.
+//
+// Returns ErrUnknownKind for other kinds, and no versions when Version is
+// not set, the kind is Unversioned, or the directory is overridden without
+// the app path (e.g., by an EnvOverrides variable with AppendAppName false).
+func (d *PlatformDirs) Versions(kind string) ([]string, error) {
+	dt, err := versionedKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	return d.versions(dt), nil
+}
+
+// PreviousVersionDir returns the directory of the newest existing version
+// older than Version for a user directory kind, such as
+// ~/.config/myapp/2.4 when Version is "3". It is the directory
+// Config.VersionFallback searches after the current one.
+func (d *PlatformDirs) PreviousVersionDir(kind string) (string, bool) {
+	dt, err := versionedKind(kind)
+	if err != nil {
+		return "", false
+	}
+	dir := d.previousVersionDir(dt)
+	return dir, dir != ""
+}
+
+// CopyForward seeds the current version's directory for a user directory
+// kind from the previous version (see PreviousVersionDir) and returns the
+// directory it copied from. If upgrade is nil, the previous directory is
+// copied as is; otherwise the current directory is created empty and
+// upgrade(from, to) writes its contents, for data formats that change
+// between versions. If upgrade fails, the current directory is removed so
+// a later call can retry.
+//
+// Nothing happens, and "" is returned, when the current directory already
+// exists or there is no previous version. Like Migrate, CopyForward works
+// on the real filesystem, not Config.FS.
+//
+// Example:
+//
+//	from, err := dirs.CopyForward("user-config", func(from, to string) error {
+//		return convertConfig(filepath.Join(from, "config.toml"), filepath.Join(to, "config.toml"))
+//	})
+func (d *PlatformDirs) CopyForward(kind string, upgrade func(from, to string) error) (string, error) {
+	dt, err := versionedKind(kind)
+	if err != nil {
+		return "", err
+	}
+	current := d.resolveUserDirForFallbacks(dt)
+	if _, err := os.Lstat(current); !errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	from := d.previousVersionDir(dt)
+	if from == "" {
+		return "", nil
+	}
+
+	if upgrade == nil {
+		if err := os.MkdirAll(filepath.Dir(current), 0o700); err != nil {
+			return "", err
+		}
+		if err := copyTree(from, current); err != nil {
+			return "", fmt.Errorf("toolpaths: copy %s forward from %s: %w", kind, from, err)
+		}
+		return from, nil
+	}
+
+	if err := os.MkdirAll(current, 0o700); err != nil {
+		return "", err
+	}
+	if err := upgrade(from, current); err != nil {
+		_ = os.RemoveAll(current)
+		return "", fmt.Errorf("toolpaths: upgrade %s from %s: %w", kind, from, err)
+	}
+	return from, nil
+}
+
+// ---------------------------------------------------------------------
+// Internal: version enumeration
+// ---------------------------------------------------------------------
+
+// versionPlaceholder stands in for Version to find where a resolved path
+// puts it; no real directory name contains a NUL byte.
+const versionPlaceholder = "\x00version"
+
+// versionedKind returns the user directory kind named kind.
+func versionedKind(kind string) (dirType, error) {
+	for _, dt := range []dirType{userConfig, userData, userCache, userState, userLog} {
+		if dt.String() == kind {
+			return dt, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+}
+
+// versionTemplate returns the directory of dt split around the version
+// element: {parent}/{version}/{suffix}. It returns ok false when the path
+// does not contain Version.
+func (d *PlatformDirs) versionTemplate(dt dirType) (string, string, bool) {
+	if d.cfg.Version == "" {
+		return "", "", false
+	}
+	return d.splitPath(dt, versionPlaceholder, func(c *PlatformDirs) { c.cfg.Version = versionPlaceholder })
+}
+
+// splitPath resolves the primary directory of dt on a copy of d that set
+// changes to put placeholder in the path, and splits the result around it:
+// {parent}/{placeholder}/{suffix}. It returns ok false when the path does
+// not contain placeholder.
+func (d *PlatformDirs) splitPath(dt dirType, placeholder string, set func(c *PlatformDirs)) (string, string, bool) {
+	c := *d
+	c.cfg.OnXDGViolation = nil
+	set(&c)
+	dir := c.resolveUserDirForFallbacks(dt)
+
+	parent, suffix, ok := strings.Cut(dir, placeholder)
+	if !ok {
+		return "", "", false
+	}
+	return filepath.Clean(parent), strings.TrimLeft(suffix, `/\`), true
+}
+
+// versions lists the existing version directories of dt, newest first.
+func (d *PlatformDirs) versions(dt dirType) []string {
+	parent, suffix, ok := d.versionTemplate(dt)
+	if !ok {
+		return nil
+	}
+
+	var found []string
+	for _, name := range readDirNames(d.fsys, parent) {
+		if _, ok := parseVersion(name); !ok {
+			continue
+		}
+		if fi, err := d.fsys.Stat(filepath.Join(parent, name, suffix)); err == nil && fi.IsDir() {
+			found = append(found, name)
+		}
+	}
+	slices.SortStableFunc(found, func(a, b string) int { return compareVersions(b, a) })
+	return found
+}
+
+// previousVersionDir returns the directory of the newest existing version
+// older than Version, or "".
+func (d *PlatformDirs) previousVersionDir(dt dirType) string {
+	current, ok := parseVersion(d.cfg.Version)
+	if !ok {
+		return ""
+	}
+	parent, suffix, ok := d.versionTemplate(dt)
+	if !ok {
+		return ""
+	}
+	for _, name := range d.versions(dt) {
+		if v, _ := parseVersion(name); v.compare(current) < 0 {
+			return filepath.Join(parent, name, suffix)
+		}
+	}
+	return ""
+}
+
+// fallbackVersionDir is previousVersionDir when Config.VersionFallback is
+// set, and "" otherwise.
+func (d *PlatformDirs) fallbackVersionDir(dt dirType) string {
+	if !d.cfg.VersionFallback {
+		return ""
+	}
+	switch dt { //nolint:exhaustive // only user dir types are versioned
+	case userConfig, userData, userCache, userState, userLog:
+		return d.previousVersionDir(dt)
+	default:
+		return ""
+	}
+}
+
+// readDirNames returns the names in dir, or nil if it cannot be read.
+func readDirNames(fsys FS, dir string) []string {
+	f, err := fsys.Open(dir)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	rd, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return nil
+	}
+	entries, err := rd.ReadDir(-1)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// semver is a parsed version: numeric release components and an optional
+// pre-release label. Build metadata is ignored.
+type semver struct {
+	release []int
+	pre     string
+}
+
+// parseVersion parses "3", "v2", "2.1.0", "1.0.0-beta.1", or "1.2+build".
+func parseVersion(s string) (semver, bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+	if s == "" {
+		return semver{}, false
+	}
+
+	var v semver
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semver{}, false
+		}
+		v.release = append(v.release, n)
+	}
+	v.pre = pre
+	return v, true
+}
+
+// compare orders versions by release components, missing components
+// counting as zero, then ranks a pre-release below its release.
+func (v semver) compare(o semver) int {
+	for i := range max(len(v.release), len(o.release)) {
+		var a, b int
+		if i < len(v.release) {
+			a = v.release[i]
+		}
+		if i < len(o.release) {
+			b = o.release[i]
+		}
+		if c := cmp.Compare(a, b); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case v.pre == o.pre:
+		return 0
+	case v.pre == "":
+		return 1
+	case o.pre == "":
+		return -1
+	default:
+		return strings.Compare(v.pre, o.pre)
+	}
+}
+
+// compareVersions compares two version names that parse.
+func compareVersions(a, b string) int {
+	va, _ := parseVersion(a)
+	vb, _ := parseVersion(b)
+	return va.compare(vb)
+}
@@ -0,0 +1,258 @@
+package toolpaths_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tbhb/toolpaths-go"
+)
+
+func TestUnversioned(t *testing.T) {
+	home := p(testBase(), "home")
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:     "myapp",
+		Version:     "3",
+		Unversioned: []string{"user-config", "user-data"},
+		Platform:    toolpaths.PlatformLinux,
+		HomeDir:     home,
+		LookupEnv:   mapEnv(nil),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, p(home, ".config", "myapp"), dirs.UserConfigDir())
+	assert.Equal(t, p(home, ".local", "share", "myapp"), dirs.UserDataDir())
+	assert.Equal(t, p(home, ".cache", "myapp", "3"), dirs.UserCacheDir())
+	assert.Equal(t, p("/etc", "xdg", "myapp", "3"), dirs.SystemConfigDirs()[0])
+
+	_, err = toolpaths.NewWithConfig(toolpaths.Config{AppName: "myapp", Unversioned: []string{"config"}})
+	require.ErrorIs(t, err, toolpaths.ErrUnknownKind)
+
+	t.Run("log and state are independent", func(t *testing.T) {
+		cfg := toolpaths.Config{
+			AppName:     "myapp",
+			Version:     "3",
+			Unversioned: []string{"user-log"},
+			Platform:    toolpaths.PlatformLinux,
+			HomeDir:     home,
+			LookupEnv:   mapEnv(nil),
+		}
+		logOnly, err := toolpaths.NewWithConfig(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, p(home, ".local", "state", "myapp", "log"), logOnly.UserLogDir())
+		assert.Equal(t, p(home, ".local", "state", "myapp", "3"), logOnly.UserStateDir())
+
+		cfg.Unversioned = []string{"user-state"}
+		cfg.LookupEnv = mapEnv(map[string]string{"XDG_STATE_HOME": p(testBase(), "state")})
+		stateOnly, err := toolpaths.NewWithConfig(cfg)
+		require.NoError(t, err)
+		assert.Equal(t, p(testBase(), "state", "myapp"), stateOnly.UserStateDir())
+		assert.Equal(t, p(testBase(), "state", "myapp", "3", "log"), stateOnly.UserLogDir())
+	})
+}
+
+func TestVersions(t *testing.T) {
+	home := p(testBase(), "home")
+	app := p(home, ".config", "myapp")
+	mem := toolpaths.NewMapFS()
+	for _, name := range []string{"1", "2.4", "2.10", "v3.0.0-beta", "3", "4", "notes"} {
+		require.NoError(t, mem.MkdirAll(p(app, name), 0o700))
+	}
+	dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+		AppName:   "myapp",
+		Version:   "3",
+		Platform:  toolpaths.PlatformLinux,
+		HomeDir:   home,
+		LookupEnv: mapEnv(nil),
+		FS:        mem,
+	})
+	require.NoError(t, err)
+
+	versions, err := dirs.Versions("user-config")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "3", "v3.0.0-beta", "2.10", "2.4", "1"}, versions)
+
+	prev, ok := dirs.PreviousVersionDir("user-config")
+	require.True(t, ok)
+	assert.Equal(t, p(app, "v3.0.0-beta"), prev, "a pre-release is older than its release")
+
+	_, ok = dirs.PreviousVersionDir("user-cache")
+	assert.False(t, ok)
+	_, err = dirs.Versions("system-config")
+	require.ErrorIs(t, err, toolpaths.ErrUnknownKind)
+
+	t.Run("unversioned kinds have no versions", func(t *testing.T) {
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:     "myapp",
+			Version:     "3",
+			Unversioned: []string{"user-config"},
+			Platform:    toolpaths.PlatformLinux,
+			HomeDir:     home,
+			LookupEnv:   mapEnv(nil),
+			FS:          mem,
+		})
+		require.NoError(t, err)
+		versions, err := dirs.Versions("user-config")
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	})
+
+	t.Run("version inside the app path", func(t *testing.T) {
+		mem := toolpaths.NewMapFS()
+		local := p(home, "AppData", "Local")
+		require.NoError(t, mem.MkdirAll(p(local, "Acme", "myapp", "2", "cache"), 0o700))
+		require.NoError(t, mem.MkdirAll(p(local, "Acme", "myapp", "1"), 0o700))
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName:   "myapp",
+			AppAuthor: "Acme",
+			Version:   "3",
+			Platform:  toolpaths.PlatformWindows,
+			HomeDir:   home,
+			LookupEnv: mapEnv(nil),
+			FS:        mem,
+		})
+		require.NoError(t, err)
+		prev, ok := dirs.PreviousVersionDir("user-cache")
+		require.True(t, ok)
+		assert.Equal(t, p(local, "Acme", "myapp", "2", "cache"), prev)
+		versions, err := dirs.Versions("user-cache")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2"}, versions, "versions without a cache directory are skipped")
+	})
+}
+
+func TestVersionFallback(t *testing.T) {
+	home := p(testBase(), "home")
+	mem := toolpaths.NewMapFS()
+	require.NoError(t, mem.WriteFile(p(home, ".config", "myapp", "2", "config.toml"), nil, 0o600))
+	require.NoError(t, mem.WriteFile(p(home, ".cache", "myapp", "2", "index"), nil, 0o600))
+
+	cfg := toolpaths.Config{
+		AppName:   "myapp",
+		Version:   "3",
+		Platform:  toolpaths.PlatformLinux,
+		HomeDir:   home,
+		LookupEnv: mapEnv(nil),
+		FS:        mem,
+	}
+	dirs, err := toolpaths.NewWithConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{p(home, ".config", "myapp", "3")}, dirs.UserConfigDirs(), "off by default")
+	_, ok := dirs.FindConfigFile("config.toml")
+	assert.False(t, ok)
+
+	cfg.VersionFallback = true
+	dirs, err = toolpaths.NewWithConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		p(home, ".config", "myapp", "3"),
+		p(home, ".config", "myapp", "2"),
+	}, dirs.UserConfigDirs())
+	got, ok := dirs.FindConfigFile("config.toml")
+	require.True(t, ok)
+	assert.Equal(t, p(home, ".config", "myapp", "2", "config.toml"), got)
+	got, ok = dirs.FindCacheFile("index")
+	require.True(t, ok)
+	assert.Equal(t, p(home, ".cache", "myapp", "2", "index"), got)
+
+	require.NoError(t, mem.WriteFile(p(home, ".config", "myapp", "3", "config.toml"), nil, 0o600))
+	got, ok = dirs.FindConfigFile("config.toml")
+	require.True(t, ok)
+	assert.Equal(t, p(home, ".config", "myapp", "3", "config.toml"), got, "the current version wins")
+
+	x, err := dirs.Explain("user-config")
+	require.NoError(t, err)
+	assert.Equal(t, p(home, ".config", "myapp", "3"), x.Path)
+	assert.Equal(t, dirs.UserConfigDirs(), x.Paths)
+	for _, step := range x.Steps {
+		if step.Strategy == toolpaths.StrategyPreviousVersion {
+			assert.Equal(t, p(home, ".config", "myapp", "2"), step.Path)
+			assert.False(t, step.Chosen)
+		}
+	}
+}
+
+func TestVersionFallbackNotMigrated(t *testing.T) {
+	home := setTestHomeXDG(t)
+	writeFile(t, p(home, ".config", "myapp", "2", "config.toml"), "v2\n")
+	writeFile(t, p(home, ".config", "myapp", "3", "config.toml"), "v3\n")
+
+	cfg := toolpaths.Config{AppName: "myapp", Version: "3", VersionFallback: true, Platform: toolpaths.PlatformLinux}
+	dirs, err := toolpaths.NewWithConfig(cfg)
+	require.NoError(t, err)
+	require.Contains(t, dirs.UserConfigDirs(), p(home, ".config", "myapp", "2"))
+	report, err := dirs.Migrate(toolpaths.MigrateOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Actions, "moving the old version would break downgrades; see CopyForward")
+
+	cfg.Platform = toolpaths.PlatformMacOS
+	dirs, err = toolpaths.NewWithConfig(cfg)
+	require.NoError(t, err)
+	report, err = dirs.Migrate(toolpaths.MigrateOptions{})
+	require.NoError(t, err)
+	var moved []string
+	for _, a := range report.Actions {
+		if a.Op == "move" {
+			moved = append(moved, a.From)
+		}
+	}
+	assert.Equal(t, []string{p(home, ".config", "myapp", "3")}, moved, "only the XDG fallback of this version")
+	assert.Equal(t, "v2\n", readFile(t, p(home, ".config", "myapp", "2", "config.toml")))
+}
+
+func TestCopyForward(t *testing.T) {
+	newDirs := func(t *testing.T) (*toolpaths.PlatformDirs, string) {
+		t.Helper()
+		home := setTestHomeXDG(t)
+		writeFile(t, p(home, ".config", "myapp", "2", "config.toml"), "v2\n")
+		dirs, err := toolpaths.NewWithConfig(toolpaths.Config{
+			AppName: "myapp", Version: "3", Platform: toolpaths.PlatformLinux,
+		})
+		require.NoError(t, err)
+		return dirs, p(home, ".config", "myapp")
+	}
+
+	t.Run("copy", func(t *testing.T) {
+		dirs, app := newDirs(t)
+		from, err := dirs.CopyForward("user-config", nil)
+		require.NoError(t, err)
+		assert.Equal(t, p(app, "2"), from)
+		assert.Equal(t, "v2\n", readFile(t, p(app, "3", "config.toml")))
+		assert.FileExists(t, p(app, "2", "config.toml"))
+
+		from, err = dirs.CopyForward("user-config", nil)
+		require.NoError(t, err)
+		assert.Empty(t, from, "the current version already exists")
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		dirs, app := newDirs(t)
+		from, err := dirs.CopyForward("user-config", func(from, to string) error {
+			data, err := os.ReadFile(p(from, "config.toml"))
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(p(to, "settings.toml"), append([]byte("# upgraded\n"), data...), 0o600)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, p(app, "2"), from)
+		assert.Equal(t, "# upgraded\nv2\n", readFile(t, p(app, "3", "settings.toml")))
+	})
+
+	t.Run("failed upgrade", func(t *testing.T) {
+		dirs, app := newDirs(t)
+		errUpgrade := errors.New("bad config")
+		_, err := dirs.CopyForward("user-config", func(string, string) error { return errUpgrade })
+		require.ErrorIs(t, err, errUpgrade)
+		assert.NoDirExists(t, p(app, "3"), "removed so a later call can retry")
+	})
+
+	t.Run("no previous version", func(t *testing.T) {
+		dirs, _ := newDirs(t)
+		from, err := dirs.CopyForward("user-data", nil)
+		require.NoError(t, err)
+		assert.Empty(t, from)
+	})
+}
@@ -3,43 +3,66 @@
 package toolpaths
 
 import (
-	"os"
 	"path/filepath"
 
 	"golang.org/x/sys/windows"
 )
 
-func windowsRoamingAppData() string {
-	// When userHomeDirFunc is overridden (for testing), use home-based paths
-	if homeDirFuncOverridden {
-		return filepath.Join(userHomeDir(), "AppData", "Roaming")
+func (d *PlatformDirs) windowsRoamingAppData() string {
+	// When the home directory is injected (or overridden for testing), use home-based paths
+	if d.homeOverridden() {
+		return filepath.Join(d.homeDir(), "AppData", "Roaming")
 	}
 	path, err := windows.KnownFolderPath(windows.FOLDERID_RoamingAppData, 0)
 	if err != nil {
 		// Fallback to environment variable
-		return os.Getenv("APPDATA")
+		return d.getenv("APPDATA")
 	}
 	return path
 }
 
-func windowsLocalAppData() string {
-	// When userHomeDirFunc is overridden (for testing), use home-based paths
-	if homeDirFuncOverridden {
-		return filepath.Join(userHomeDir(), "AppData", "Local")
+func (d *PlatformDirs) windowsLocalAppData() string {
+	// When the home directory is injected (or overridden for testing), use home-based paths
+	if d.homeOverridden() {
+		return filepath.Join(d.homeDir(), "AppData", "Local")
 	}
 	path, err := windows.KnownFolderPath(windows.FOLDERID_LocalAppData, 0)
 	if err != nil {
 		// Fallback to environment variable
-		return os.Getenv("LOCALAPPDATA")
+		return d.getenv("LOCALAPPDATA")
 	}
 	return path
 }
 
-func windowsProgramData() string {
+func (d *PlatformDirs) windowsProgramData() string {
 	path, err := windows.KnownFolderPath(windows.FOLDERID_ProgramData, 0)
 	if err != nil {
 		// Fallback to environment variable
-		return os.Getenv("ProgramData")
+		return d.getenv("ProgramData")
+	}
+	return path
+}
+
+// windowsMediaFolders maps media directories to their Known Folder IDs.
+var windowsMediaFolders = map[mediaDir]*windows.KNOWNFOLDERID{
+	mediaDesktop:   windows.FOLDERID_Desktop,
+	mediaDocuments: windows.FOLDERID_Documents,
+	mediaDownload:  windows.FOLDERID_Downloads,
+	mediaMusic:     windows.FOLDERID_Music,
+	mediaPictures:  windows.FOLDERID_Pictures,
+	mediaVideos:    windows.FOLDERID_Videos,
+	mediaTemplates: windows.FOLDERID_Templates,
+	mediaPublic:    windows.FOLDERID_Public,
+}
+
+func (d *PlatformDirs) windowsMediaDir(md mediaDir) string {
+	// When the home directory is injected (or overridden for testing), use home-based paths
+	if d.homeOverridden() {
+		return d.windowsMediaDirDefault(md)
+	}
+	path, err := windows.KnownFolderPath(windowsMediaFolders[md], 0)
+	if err != nil {
+		return d.windowsMediaDirDefault(md)
 	}
 	return path
 }